@@ -0,0 +1,153 @@
+package tlv
+
+import "sort"
+
+//Canonicalize re-encodes the interest or data held by b in canonical form: the types and
+//lengths use the shortest VAR-NUMBER, the integer fields of the schema (schema.go) the shortest
+//NonNegativeInteger, and the fields of the packet, the selectors, the meta info and the
+//signature info come in the order of the spec. the name components (of the names, the
+//exclude and the final block id) and the other values, such as the content or the parameters,
+//are kept byte for byte, so is an unknown non-critical field, which stays right after the
+//field it followed. two encodings of the same packet give the same bytes, so the result can
+//be compared or hashed.
+//the signature of a data is not recomputed, it does not verify anymore if the encoding changed.
+//the ParametersSha256DigestComponent of an interest covers the header of its parameters, it is
+//checked on b and recomputed for the canonical encoding.
+//the packet must be valid once reordered, the error is the one Validate gives for the
+//canonical encoding (a *TlvError, *UnknownTypeError or *ValueError)
+func Canonicalize(b []byte) ([]byte, error) {
+	t, err, ts, ls := TlvFromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return nil, &TlvError{Type: t.T, Offset: ts + ls + int(t.L), Err: ErrTrailingBytes}
+	}
+	if t.T != INTEREST && t.T != DATA {
+		return nil, unknownPacketError(t.T)
+	}
+	if t.T == INTEREST {
+		if err := checkParametersDigest(t); err != nil {
+			return nil, errorAt(err, ts+ls)
+		}
+	}
+	e, err := canonicalElement(t, 0)
+	if err != nil {
+		return nil, errorAt(err, ts+ls)
+	}
+	result := e.Encode()
+	if t.T == INTEREST {
+		r, _, _, _ := TlvFromBytes(result)
+		if err := setParametersDigest(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := Validate(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//the fields of the nested tlvs Canonicalize walks, in their order, the one used by Validate.
+//the interest is not here, its rules depend on its format (interestRulesOf).
+//the key locators hold a single field, they are only walked to reach their name
+var fieldOrder = map[uint64][]fieldRule{
+	DATA:                      dataRules,
+	SELECTORS:                 selectorRules,
+	META_INFO:                 metaInfoRules,
+	SIGNATURE_INFO:            signatureInfoRules,
+	FORWARDING_HINT:           forwardingHintRules,
+	KEY_LOCATOR:               {{typ: NAME}, {typ: KEY_DIGEST}},
+	PUBLISHER_PUB_KEY_LOCATOR: {{typ: KEY_LOCATOR}},
+}
+
+//builds the canonical tree of the field t, depth being its nesting level.
+//offsets of the errors are relative to the value of t
+func canonicalElement(t Tlv, depth int) (*Element, error) {
+	switch t.T {
+	case NAME, EXCLUDE, FINAL_BLOCK_ID:
+		return canonicalComponents(t)
+	case INTEREST:
+		return canonicalFields(t, interestRulesOf(t), depth)
+	}
+	if rules, ok := fieldOrder[t.T]; ok {
+		return canonicalFields(t, rules, depth)
+	}
+	//an opaque value (content, parameters, signature value ...)
+	return NewElement(t.T, t.V), nil
+}
+
+//the fields of t in the order of the rules. the integers of the rules are re-encoded and their
+//nested tlvs walked, the unknown fields are kept as they are
+func canonicalFields(t Tlv, rules []fieldRule, depth int) (*Element, error) {
+	children := []*Element{}
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return nil, errorAt(err, i)
+		}
+		var c *Element
+		switch {
+		case ruleIndex(rules, f.T) < 0:
+			c = NewElement(f.T, f.V)
+		case isKind(f.T, KindNonNegativeInteger):
+			x, err := DecodeNonNegativeIntegerValue(f)
+			if err != nil {
+				return nil, errorAt(err, i+ts+ls)
+			}
+			c = NewElement(f.T, EncodeNonNegativeInteger(x))
+		default:
+			if isKind(f.T, KindNested) && depth+1 >= DefaultMaxDepth {
+				return nil, &TlvError{Type: f.T, Offset: i, Err: ErrTooDeep}
+			}
+			if c, err = canonicalElement(f, depth+1); err != nil {
+				return nil, errorAt(err, i+ts+ls)
+			}
+		}
+		children = append(children, c)
+		i += ts + ls + int(f.L)
+	}
+	sortFields(children, rules)
+	return NewNestedElement(t.T, children...), nil
+}
+
+//the components of a name, an exclude or a final block id are what identifies the name,
+//only their headers are re-encoded
+func canonicalComponents(t Tlv) (*Element, error) {
+	children := []*Element{}
+	for i := 0; i < len(t.V); {
+		c, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return nil, errorAt(err, i)
+		}
+		children = append(children, NewElement(c.T, c.V))
+		i += ts + ls + int(c.L)
+	}
+	return NewNestedElement(t.T, children...), nil
+}
+
+//puts the fields in the order of the rules, a field with no rule keeps its place
+//after the known field that comes before it
+func sortFields(fields []*Element, rules []fieldRule) {
+	type ranked struct {
+		rank int
+		e    *Element
+	}
+	r := make([]ranked, len(fields))
+	last := -1
+	for i, f := range fields {
+		for j := range rules {
+			if rules[j].typ == f.Type {
+				last = j
+				break
+			}
+		}
+		r[i] = ranked{last, f}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].rank < r[j].rank
+	})
+	for i := range r {
+		fields[i] = r[i].e
+	}
+}
@@ -0,0 +1,46 @@
+package tlv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalizeKeepsNameComponents(t *testing.T) {
+	//a typed component numbered like FRESHNESS_PERIOD, its value is not an integer to re-encode
+	for _, value := range []string{`x"0005"`, `x"000005"`} {
+		b, err := ParseText(`INTEREST { NAME { NAME_COMPONENT "a" 0x19 ` + value + ` } NONCE x"01020304" }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(b); err != nil {
+			t.Fatalf("%s: Validate: %v", value, err)
+		}
+		c, err := Canonicalize(b)
+		if err != nil {
+			t.Fatalf("%s: Canonicalize: %v", value, err)
+		}
+		if !bytes.Equal(c, b) {
+			t.Errorf("%s: Canonicalize changed the name:\n% x\n% x", value, b, c)
+		}
+	}
+}
+
+func TestCanonicalizeReencodesSchemaIntegers(t *testing.T) {
+	b, err := ParseText(`DATA { NAME { NAME_COMPONENT "a" } CONTENT x"0005"
+		META_INFO { FRESHNESS_PERIOD x"00000005" } SIGNATURE_INFO { SIGNATURE_TYPE x"0000" } SIGNATURE_VALUE x"00" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseText(`DATA { NAME { NAME_COMPONENT "a" } META_INFO { FRESHNESS_PERIOD 5 }
+		CONTENT x"0005" SIGNATURE_INFO { SIGNATURE_TYPE 0 } SIGNATURE_VALUE x"00" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Canonicalize(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c, want) {
+		t.Errorf("got\n% x\nwant\n% x", c, want)
+	}
+}
@@ -8,10 +8,16 @@ const(
 	NAME                       = 0x07
 	NAME_COMPONENT             = 0x08
 	IMPLICIT_DIGEST            = 0x01
+	PARAMETERS_SHA256_DIGEST   = 0x02
 	//interest packet
 	SELECTORS                  = 0x09
 	NONCE                      = 0x0a
 	INTEREST_LIFETIME          = 0x0c
+	//interest packet, format v0.3
+	CAN_BE_PREFIX              = 0x21
+	FORWARDING_HINT            = 0x1e
+	HOP_LIMIT                  = 0x22
+	APPLICATION_PARAMETERS     = 0x24
 	//interest selectors
 	MIN_SUFFIX_COMPONENTS      = 0x0d
 	MAX_SUFFIX_COMPONENTS      = 0x0e
@@ -33,4 +39,5 @@ const(
 	SIGNATURE_TYPE             = 0x1b
 	KEY_LOCATOR                = 0x1c
 	KEY_DIGEST                 = 0x1d
+	VALIDITY_PERIOD            = 0xfd
 )
@@ -2,306 +2,582 @@ package tlv
 
 import (
 	"errors"
-	"log"
 	"ndn-router/nfd/tlv/name"
 	"ndn-router/nfd/tlv/packets"
 	"time"
 )
 
-//reads the input (bytes) swithes on the type and calls the appropriate decoder
-func Decode(packet []byte) packets.NdnPacket {
-	t, _, _, _ := TlvFromBytes(packet)
+//DecodeOptions changes the way packets are decoded, the zero value decodes like Decode
+type DecodeOptions struct {
+	//name components point into the input instead of holding a copy of their bytes
+	ZeroCopy bool
+	//the fields of a tlv are recognized in any order instead of the order of the spec,
+	//a field still can not be there twice and the required ones must be there
+	AnyOrder bool
+	//the types and lengths must use the shortest VAR-NUMBER, a non-minimal one
+	//(e.g. 0xFD 0x00 0x05) is rejected with ErrBadVarNumber
+	CanonicalVarNumbers bool
+
+	//limits for untrusted input, a packet going over one of them is rejected
+	//with a *TlvError wrapping the error given below. zero means the default
+
+	//size of the whole packet, MaxPacketSize by default (ErrPacketTooLarge)
+	MaxPacketSize int
+	//nesting levels below the packet tlv, DefaultMaxDepth by default (ErrTooDeep)
+	MaxDepth int
+	//components of a name or an exclude, DefaultMaxComponents by default (ErrTooManyComponents)
+	MaxComponents int
+	//length of the value of any field inside the packet (content, signature ...),
+	//only limited by the packet size by default (ErrFieldTooLarge)
+	MaxFieldSize int
+
+	depth int //nesting level of the fields being decoded, the packet tlv being at 0
+}
+
+const (
+	//the deepest field of an interest or a data, a key locator name component, is at depth 4
+	DefaultMaxDepth      = 16
+	DefaultMaxComponents = 256
+)
+
+func (o *DecodeOptions) packetLimit() int {
+	if o.MaxPacketSize > 0 {
+		return o.MaxPacketSize
+	}
+	return MaxPacketSize
+}
+
+func (o *DecodeOptions) depthLimit() int {
+	if o.MaxDepth > 0 {
+		return o.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+func (o *DecodeOptions) componentLimit() int {
+	if o.MaxComponents > 0 {
+		return o.MaxComponents
+	}
+	return DefaultMaxComponents
+}
+
+//reads the packet tlv held by b, checking its size. like Validate, b must hold the
+//packet and nothing else (ErrTrailingBytes)
+func (o *DecodeOptions) readPacket(b []byte) (Tlv, error) {
+	t, err, ts, ls := tlvFromBytes(b, o.packetLimit(), o.CanonicalVarNumbers)
+	if err != nil {
+		return Tlv{}, err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return Tlv{}, &TlvError{Type: t.T, Offset: ts + ls + int(t.L), Err: ErrTrailingBytes}
+	}
+	return t, nil
+}
+
+//parses the fields held by the nested tlv t into the pooled slice s, checking the depth
+//and the size of the fields. on success the fields are one level deeper than t and
+//the caller must call leave once it is done with them
+func (o *DecodeOptions) enter(s *[]Tlv, t Tlv) ([]Tlv, error) {
+	if o.depth+1 > o.depthLimit() {
+		return nil, &TlvError{Type: t.T, Offset: 0, Err: ErrTooDeep}
+	}
+	fields, err := parseInto(s, t.V, o.packetLimit(), o.CanonicalVarNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if o.MaxFieldSize > 0 {
+		offset := 0
+		for _, f := range fields {
+			if f.L > uint64(o.MaxFieldSize) {
+				return nil, &TlvError{Type: f.T, Offset: offset, Err: ErrFieldTooLarge}
+			}
+			//the header was read once already, it cannot fail
+			_, _, ts, ls, _ := readHeader(t.V[offset:], o.CanonicalVarNumbers)
+			offset += ts + ls + int(f.L)
+		}
+	}
+	o.depth++
+	return fields, nil
+}
+
+func (o *DecodeOptions) leave() {
+	o.depth--
+}
+
+//ownership of the input:
+//  - the byte slice fields of a decoded packet (Content, SignatureValue, KeyDigest,
+//    ApplicationParameters and the buffer given back by GetBuffer) are sub-slices of the
+//    input, whatever the options
+//  - with ZeroCopy the name components (of the Name, Exclude, FinalBlockId, ForwardingHint
+//    and key locator names) are also backed by the input instead of having their own copy
+//  - the Nonce is a 4 byte array and is always copied
+//the input must not be modified or reused while the packet is in use, a packet that has
+//to outlive the receive buffer must be detached first with its Detach() method
+
+//reads the input (bytes) swithes on the type and calls the appropriate decoder.
+//a packet is only given back when all of it could be decoded, otherwise the error says why:
+//  - *TlvError wrapping ErrUnknownPacketType when the outer tlv is neither an interest nor a data
+//  - *TlvError when the outer tlv itself is malformed or too large, or when b holds
+//    more than the packet (ErrTrailingBytes)
+//  - *DecodeError for a problem in the fields, with where it is (see DecodeError)
+func Decode(packet []byte) (packets.NdnPacket, error) {
+	return DecodeWithOptions(packet, DecodeOptions{})
+}
+
+//decodes without copying the name components, see the ownership rules above
+func DecodeZeroCopy(packet []byte) (packets.NdnPacket, error) {
+	return DecodeWithOptions(packet, DecodeOptions{ZeroCopy: true})
+}
+
+func DecodeWithOptions(packet []byte, opts DecodeOptions) (packets.NdnPacket, error) {
+	return decodePacket(packet, &opts)
+}
+
+//decodes the packet and gives back why it could not be decoded
+func decodePacket(packet []byte, opts *DecodeOptions) (packets.NdnPacket, error) {
+	t, err := opts.readPacket(packet)
+	if err != nil {
+		return nil, err
+	}
 	switch t.T {
 	case INTEREST:
-		resultInterest, _ := decodeInterest(t)
+		resultInterest := packets.Interest{}
+		err := decodeInterestInto(t, opts, &resultInterest)
+		if err != nil {
+			return nil, locate(err, packet)
+		}
 		resultInterest.Setbuffer(packet)
-		return resultInterest
+		return resultInterest, nil
 	case DATA:
-		resultData, _ := decodeData(t)
+		resultData := packets.Data{}
+		err := decodeDataInto(t, opts, &resultData)
+		if err != nil {
+			return nil, locate(err, packet)
+		}
 		resultData.Setbuffer(packet)
-		return resultData
+		return resultData, nil
 	default:
-		log.Println("unknown bytes")
-		return nil
+		return nil, unknownPacketError(t.T)
 	}
 }
 
 //take the value od the interest TLV and call the different decode functiond for each sub tlv
 //and return ant interest
-func decodeInterest(t Tlv) (packets.Interest, error) {
-	tlvs, _ := ParseTlvsFromBytes(t.V)
+func decodeInterest(t Tlv, opts *DecodeOptions) (packets.Interest, error) {
 	resultInterest := packets.Interest{}
-	err := decodeTlvs(
-		&resultInterest, tlvs,
-		decodeInterestName,
-		decodeInterestSelectors,
-		decodeInterestNonce,
-		decodeInterestLifeTime,
-	)
+	err := decodeInterestInto(t, opts, &resultInterest)
 	if err != nil {
 		return packets.Interest{}, err
 	}
 	return resultInterest, nil
 }
 
-func decodeData(t Tlv) (packets.Data, error) {
-	tlvs, _ := ParseTlvsFromBytes(t.V)
+//decodes into an existing (empty) interest, used to fill interests taken from the pool
+//the format of the interest is detected from its fields (see interestFormat) and recorded on it
+func decodeInterestInto(t Tlv, opts *DecodeOptions, resultInterest *packets.Interest) error {
+	if err := checkInterestFormat(t); err != nil {
+		return fieldError(err, t)
+	}
+	format := interestFormat(t)
+	rules := interestV03Rules
+	if format == packets.InterestFormatV02 {
+		rules = interestV02Rules
+	}
+	if err := decodeFields(t, resultInterest, rules, opts); err != nil {
+		return err
+	}
+	resultInterest.SetFormat(format)
+	//most interests have neither parameters nor digest, the fields are not walked again for them
+	if resultInterest.HasApplicationParameters || hasParametersDigest(resultInterest.GetName()) {
+		return fieldError(checkParametersDigest(t), t)
+	}
+	return nil
+}
+
+func decodeData(t Tlv, opts *DecodeOptions) (packets.Data, error) {
 	resultData := packets.Data{}
-	err := decodeTlvs(
-		&resultData, tlvs,
-		decodeDataName,
-		decodeDataMetaInfo,
-		decodeDataContent,
-		decodeDataSignature,
-	)
+	err := decodeDataInto(t, opts, &resultData)
 	if err != nil {
 		return packets.Data{}, err
 	}
 	return resultData, nil
 }
 
-// a decoder is any function with this prototype
-type decoder func(packet interface{}, tlvs []Tlv) ([]Tlv, error)
+//decodes into an existing (empty) data, used to fill data packets taken from the pool
+func decodeDataInto(t Tlv, opts *DecodeOptions, resultData *packets.Data) error {
+	return decodeFields(t, resultData, dataRules, opts)
+}
+
+//a type is critical if it is in the range reserved for the base packet format (< 32) or odd.
+//a decoder that does not recognize a critical type must drop the packet,
+//non-critical types it does not recognize are ignored
+func IsCritical(t uint64) bool {
+	return t < 32 || t%2 == 1
+}
 
 //+++++++++++++++++++++++++++++++++++++++
 //might find a way to add concurrency here to have concurrency on the same packet
 //+++++++++++++++++++++++++++++++++++++++
-func decodeTlvs(packet interface{}, tlvs []Tlv, dec ...decoder) error {
-	//var err error
-	for _, d := range dec {
-		tlvs, _ = d(packet, tlvs)
-		/*if err != nil {
-		  return err
-		}*/
+//decodes the fields of t with the decoders of its rules (see schema.go), the fields coming
+//in the order of the rules or, with AnyOrder, in any order. a duplicate or out of order field,
+//a missing required one and an unrecognized critical type are errors (the evolvability rule),
+//unrecognized non-critical types are skipped. errors get t in their path
+func decodeFields(t Tlv, packet interface{}, rules []fieldRule, opts *DecodeOptions) error {
+	scratch := getTlvs()
+	defer putTlvs(scratch)
+	fields, err := opts.enter(scratch, t) // from []bytes to []Tlv
+	if err != nil {
+		return fieldError(err, t)
+	}
+	defer opts.leave()
+	k := newFieldTracker(rules, opts.AnyOrder)
+	for _, f := range fields {
+		r, err := k.add(f.T)
+		switch {
+		case err != nil:
+			return fieldError(fieldError(err, f), t)
+		case r < 0:
+			if IsCritical(f.T) {
+				return fieldError(fieldError(&UnknownTypeError{Type: f.T, Parent: t.T}, f), t)
+			}
+		case rules[r].decode != nil:
+			if err := rules[r].decode(packet, f, opts); err != nil {
+				return fieldError(fieldError(err, f), t)
+			}
+		}
+	}
+	if typ, ok := k.missing(); ok {
+		//like Validate, a missing field is reported at the end of the tlv that should hold it
+		return fieldError(&TlvError{Type: typ, Offset: len(t.V), Err: ErrMissingField}, t)
 	}
 	return nil
 }
 
 //takes the name tlv and calls the decode name to get the name back and sets the result's name
-func decodeInterestName(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeInterestName : --- no tlvs to read ---")
-	}
+func decodeInterestName(packet interface{}, t Tlv, opts *DecodeOptions) error {
 	//decodeName is common to both interest and data
-	name, err := decodeName(tlvs[0]) //the name tlv is the first one tlvs[0]
+	//the name of a pooled interest is reused
+	name, err := decodeNameInto(packet.(*packets.Interest).GetName(), t, opts)
 	if err != nil {
-		return tlvs, err
+		return err
 	}
 	packet.(*packets.Interest).SetName(name) //the result
-	return tlvs[1:], nil                     //get rid of the processed tlv
+	return nil
 }
 
 //get name of either interest or data
-func decodeName(t Tlv) (name.Name, error) {
+func decodeName(t Tlv, opts *DecodeOptions) (name.Name, error) {
+	return decodeNameInto(nil, t, opts)
+}
+
+//decodes the name reusing the memory of dst for the components
+func decodeNameInto(dst name.Name, t Tlv, opts *DecodeOptions) (name.Name, error) {
 	if t.T != NAME {
 		return nil, errors.New("--- Decode Name --- : unexpected type")
 	}
 	//since the name tlv is multi level we do the same as we did with the outer most tlv
-	componentTlvs, err := ParseTlvsFromBytes(t.V) // from []bytes to []Tlv
+	scratch := getTlvs()
+	defer putTlvs(scratch)
+	componentTlvs, err := opts.enter(scratch, t) // from []bytes to []Tlv
 	if err != nil {
-		return nil, err
+		return nil, fieldError(err, t)
+	}
+	defer opts.leave()
+	if len(componentTlvs) > opts.componentLimit() {
+		return nil, fieldError(&TlvError{Type: t.T, Offset: 0, Err: ErrTooManyComponents}, t)
 	}
-	components := []name.Component{}
-	for _, t := range componentTlvs {
-		c, err := decodeNameComponent(t)
+	components := dst[:0]
+	if cap(components) < len(componentTlvs) {
+		components = make(name.Name, 0, len(componentTlvs))
+	}
+	for _, ct := range componentTlvs {
+		c, err := decodeNameComponent(ct, t.T, opts)
 		if err != nil {
-			return nil, err
+			return nil, fieldError(err, t)
 		}
 		components = append(components, c)
 	}
-	return name.NewName(components...), nil
+	return components, nil
 }
 
-func decodeNameComponent(t Tlv) (name.Component, error) {
-	if t.T == ANY {
-		return name.Any, nil
+//decodes a component of the tlv of type parent (a name, a final block id), the component
+//types are the ones Validate accepts (see checkNameComponent), Any only being in excludes
+func decodeNameComponent(t Tlv, parent uint64, opts *DecodeOptions) (name.Component, error) {
+	if err := checkNameComponent(t, parent); err != nil {
+		return name.Component{}, fieldError(err, t)
+	}
+	//take the value which is bytes and turn it into a component
+	var c name.Component
+	if opts.ZeroCopy {
+		c = name.ComponentFromBytesNoCopy(t.V)
+	} else {
+		c = name.ComponentFromBytes(t.V)
 	}
 	if t.T != NAME_COMPONENT {
-		return name.Component{}, errors.New("--- Decode Name --- : unexpected type")
+		c.Type = t.T
 	}
-	//take the value which is bytes and turn it into a component
-	c := name.ComponentFromBytes(t.V)
 	return c, nil
 }
 
-func decodeDataName(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeDataName : --- no tlvs to read ---")
-	}
+func decodeDataName(packet interface{}, t Tlv, opts *DecodeOptions) error {
 	//decodeName is common to both interest and data
-	name, err := decodeName(tlvs[0]) //the name tlv is the first one tlvs[0]
+	name, err := decodeNameInto(packet.(*packets.Data).GetName(), t, opts)
 	if err != nil {
-		return tlvs, err
+		return err
 	}
 	packet.(*packets.Data).SetName(name) //the result
-	return tlvs[1:], nil                 //get rid of the processed tlv
+	return nil
 }
 
-func decodeInterestSelectors(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeInterestSelectors : --- no tlvs to read ---")
-	}
-	t := tlvs[0]
-	if t.T != SELECTORS {
-		return tlvs, errors.New("--- Decode Interest Selectors --- : unexpected type")
-	}
-	selectorFields, _ := ParseTlvsFromBytes(t.V) // from []bytes to []Tlv
-	for _, field := range selectorFields {
-		err := decodeSelectorField(field, packet.(*packets.Interest))
-		if err != nil {
-			return nil, err
-		}
-	}
-	return tlvs[1:], nil
+//each selector is decoded by decodeSelectorField
+func decodeInterestSelectors(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	return decodeFields(t, packet, selectorRules, opts)
 }
 
-func decodeSelectorField(field Tlv, packet *packets.Interest) error {
+func decodeSelectorField(p interface{}, field Tlv, opts *DecodeOptions) error {
+	packet := p.(*packets.Interest)
 	switch field.T {
 	case MIN_SUFFIX_COMPONENTS:
-		x := DecodeNonNegativeInteger(field.V)
+		x, err := DecodeNonNegativeIntegerValue(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.Selector.SetMinSuffixComponents(x)
 	case MAX_SUFFIX_COMPONENTS:
-		x := DecodeNonNegativeInteger(field.V)
+		x, err := DecodeNonNegativeIntegerValue(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.Selector.SetMaxSuffixComponents(x)
 	case PUBLISHER_PUB_KEY_LOCATOR:
-		x, _, err := decodeKeyLocator(field)
-		if err == nil {
-			packet.Selector.SetPublisherPublicKeyLocator(x)
+		x, err := decodePublisherPublicKeyLocator(field, opts)
+		if err != nil {
+			return fieldError(err, field)
 		}
+		packet.Selector.SetPublisherPublicKeyLocator(x)
 	case EXCLUDE:
-		x, _ := decodeExclude(field)
+		x, err := decodeExclude(field, opts)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.Selector.SetExclude(x)
 	case CHILD_SELECTOR:
-		x := DecodeNonNegativeInteger(field.V)
+		x, err := DecodeNonNegativeIntegerValue(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.Selector.SetChildSelector(x)
 	case MUST_BE_FRESH:
-		x := decodeMustBeFresh(field)
+		x, err := decodeMustBeFresh(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.Selector.SetMustBeFresh(x)
 	}
 	return nil
 }
 
-func decodeExclude(t Tlv) (name.Exclude, error) {
+func decodeExclude(t Tlv, opts *DecodeOptions) (name.Exclude, error) {
 	if t.T != EXCLUDE {
 		return nil, errors.New("--- Decode Exclude --- : unexpected type")
 	}
 	//since the exclude tlv is multi level we do the same as we did with the outer most tlv
-	componentTlvs, err := ParseTlvsFromBytes(t.V) // from []bytes to []Tlv
+	scratch := getTlvs()
+	defer putTlvs(scratch)
+	componentTlvs, err := opts.enter(scratch, t) // from []bytes to []Tlv
 	if err != nil {
-		return nil, err
-	}
-	components := []name.Component{}
-	for _, t := range componentTlvs {
-		c, err := decodeNameComponent(t)
+		return nil, fieldError(err, t)
+	}
+	defer opts.leave()
+	if len(componentTlvs) > opts.componentLimit() {
+		return nil, fieldError(&TlvError{Type: t.T, Offset: 0, Err: ErrTooManyComponents}, t)
+	}
+	components := make([]name.Component, 0, len(componentTlvs))
+	for _, ct := range componentTlvs {
+		var c name.Component
+		switch ct.T {
+		case NAME_COMPONENT:
+			c, err = decodeNameComponent(ct, t.T, opts)
+		case ANY:
+			//Any is an empty flag, like Validate checks it
+			c = name.Any
+			if _, err = DecodeFlagValue(ct); err != nil {
+				err = fieldError(err, ct)
+			}
+		default:
+			err = fieldError(&UnknownTypeError{Type: ct.T, Parent: t.T}, ct)
+		}
 		if err != nil {
-			return nil, err
+			return nil, fieldError(err, t)
 		}
 		components = append(components, c)
 	}
 	return name.NewExclude(components...), nil
 }
 
-func decodeMustBeFresh(t Tlv) bool {
-	return true
+func decodeMustBeFresh(t Tlv) (bool, error) {
+	return DecodeFlagValue(t)
 }
 
-func decodeInterestNonce(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeDataName : --- no tlvs to read ---")
+//the publisher public key locator holds a key locator tlv
+func decodePublisherPublicKeyLocator(t Tlv, opts *DecodeOptions) (packets.KeyLocator, error) {
+	if t.T != PUBLISHER_PUB_KEY_LOCATOR {
+		return packets.KeyLocator{}, errors.New("DecodePublisherPublicKeyLocator : --- unexpected type ---")
+	}
+	scratch := getTlvs()
+	defer putTlvs(scratch)
+	fields, err := opts.enter(scratch, t)
+	if err != nil {
+		return packets.KeyLocator{}, fieldError(err, t)
 	}
-	t := tlvs[0]
-	if t.T != NONCE {
-		return tlvs, errors.New("--- Decode Interest Nonce --- : unexpected type")
+	defer opts.leave()
+	//a single key locator, checked like Validate does
+	if _, _, err := singleField(t, KEY_LOCATOR); err != nil {
+		return packets.KeyLocator{}, fieldError(err, t)
 	}
-	nonce := [4]byte{}
-	for i := 0; i < len(nonce); i++ {
-		nonce[i] = t.V[i]
+	if fields[0].T != KEY_LOCATOR {
+		return packets.KeyLocator{}, fieldError(fieldError(&UnknownTypeError{Type: fields[0].T, Parent: t.T}, fields[0]), t)
 	}
-	packet.(*packets.Interest).SetNonce(nonce)
-	return tlvs[1:], nil
+	keyLocator, _, err := decodeKeyLocator(fields[0], opts)
+	return keyLocator, fieldError(err, t)
 }
 
-func decodeInterestLifeTime(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeInterestLifeTime : --- no tlvs to read ---")
+func decodeInterestCanBePrefix(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	x, err := DecodeFlagValue(t)
+	if err != nil {
+		return fieldError(err, t)
 	}
-	t := tlvs[0]
-	if t.T != INTEREST_LIFETIME {
-		return tlvs, errors.New("--- DecodeInterestLifeTime --- : unexpected type")
+	packet.(*packets.Interest).SetCanBePrefix(x)
+	return nil
+}
+
+func decodeInterestMustBeFresh(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	x, err := DecodeFlagValue(t)
+	if err != nil {
+		return fieldError(err, t)
 	}
-	lifeTime := DecodeNonNegativeInteger(t.V)
-	packet.(*packets.Interest).SetInterestLifetime(time.Duration(lifeTime))
-	return tlvs[1:], nil
+	packet.(*packets.Interest).SetMustBeFresh(x)
+	return nil
+}
+
+//each name of the forwarding hint is decoded by decodeForwardingHintName
+func decodeInterestForwardingHint(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	return decodeFields(t, packet, forwardingHintRules, opts)
+}
+
+func decodeForwardingHintName(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	n, err := decodeName(t, opts)
+	if err != nil {
+		return err
+	}
+	i := packet.(*packets.Interest)
+	i.SetForwardingHint(append(i.GetForwardingHint(), n))
+	return nil
 }
 
-func decodeDataMetaInfo(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeDataMetaInfo : --- no tlvs to read ---")
+func decodeInterestNonce(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	v, err := DecodeFixedValue(t, 4)
+	if err != nil {
+		return fieldError(err, t)
 	}
-	t := tlvs[0]
-	if t.T != META_INFO {
-		return tlvs, errors.New("--- DecodeDataMetaInfo --- : unexpected type")
+	nonce := [4]byte{}
+	copy(nonce[:], v)
+	packet.(*packets.Interest).SetNonce(nonce)
+	return nil
+}
+
+func decodeInterestLifeTime(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	lifeTime, err := DecodeNonNegativeIntegerValue(t)
+	if err != nil {
+		return fieldError(err, t)
 	}
-	metaFields, _ := ParseTlvsFromBytes(t.V) // from []bytes to []Tlv
-	for _, field := range metaFields {
-		err := decodeMetaField(field, packet.(*packets.Data))
-		if err != nil {
-			return nil, err
-		}
+	packet.(*packets.Interest).SetInterestLifetime(time.Duration(lifeTime))
+	return nil
+}
+
+func decodeInterestHopLimit(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	v, err := DecodeFixedValue(t, 1)
+	if err != nil {
+		return fieldError(err, t)
 	}
-	return tlvs[1:], nil
+	packet.(*packets.Interest).SetHopLimit(v[0])
+	return nil
+}
+
+//the parameters are a sub-slice of the input, their digest is checked once all the fields are decoded
+func decodeInterestApplicationParameters(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	packet.(*packets.Interest).SetApplicationParameters(t.V)
+	return nil
+}
+
+//each field of the meta info is decoded by decodeMetaField
+func decodeDataMetaInfo(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	return decodeFields(t, packet, metaInfoRules, opts)
 }
 
-func decodeMetaField(field Tlv, packet *packets.Data) error {
+func decodeMetaField(p interface{}, field Tlv, opts *DecodeOptions) error {
+	packet := p.(*packets.Data)
 	switch field.T {
 	case CONTENT_TYPE:
-		x := DecodeNonNegativeInteger(field.V)
+		x, err := DecodeNonNegativeIntegerValue(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.MetaInfo.SetContentType(packets.ContentType(x))
 	case FRESHNESS_PERIOD:
-		x := DecodeNonNegativeInteger(field.V)
+		x, err := DecodeNonNegativeIntegerValue(field)
+		if err != nil {
+			return fieldError(err, field)
+		}
 		packet.MetaInfo.SetFreshnessPeriod(time.Duration(x))
 
 	case FINAL_BLOCK_ID:
-		t, _, _, _ := TlvFromBytes(field.V)
-		x, err := decodeNameComponent(t)
+		//a single component and nothing after it, checked like Validate does
+		if err := validateFinalBlockId(field); err != nil {
+			return fieldError(err, field)
+		}
+		scratch := getTlvs()
+		defer putTlvs(scratch)
+		fields, err := opts.enter(scratch, field)
 		if err != nil {
-			return err
+			return fieldError(err, field)
+		}
+		defer opts.leave()
+		if len(fields) < 1 {
+			return fieldError(errors.New("DecodeMetaInfo : --- empty FinalBlockId ---"), field)
+		}
+		x, err := decodeNameComponent(fields[0], field.T, opts)
+		if err != nil {
+			return fieldError(err, field)
 		}
 		packet.MetaInfo.SetFinalBlockID(x)
 	}
 	return nil
 }
 
-func decodeDataContent(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 1 {
-		return nil, errors.New("DecodeDataContent : --- no tlvs to read ---")
-	}
-	t := tlvs[0]
-	if t.T != CONTENT {
-		return tlvs, errors.New("--- DecodeDataContent --- : unexpected type")
-	}
+func decodeDataContent(packet interface{}, t Tlv, opts *DecodeOptions) error {
 	//the content is []bytes, it is value of the current tlv
 	packet.(*packets.Data).SetContent(t.V)
-	return tlvs[1:], nil
+	return nil
 }
 
-func decodeDataSignature(packet interface{}, tlvs []Tlv) ([]Tlv, error) {
-	if len(tlvs) < 2 {
-		return nil, errors.New("DecodeDataContent : --- no tlvs to read ---")
-	}
-	info := tlvs[0]
-	val := tlvs[1]
-	if info.T != SIGNATURE_INFO {
-		return tlvs, errors.New("--- DecodeDataSignature ..SigInfo.. --- : unexpected type")
-	}
-	if val.T != SIGNATURE_VALUE {
-		return tlvs, errors.New("--- DecodeDataSignature ..SigVal.. --- : unexpected type")
+//the signature info and value are separate fields, each one keeps the other one's part
+//of the signature so they can be decoded in any order
+func decodeDataSignatureInfo(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	sigInfo, err := decodeSignatureInfo(t, opts)
+	if err != nil {
+		return err
 	}
-	valBytes, _ := decodeSignatureValue(val)
-	sigInfo, _ := decodeSignatureInfo(info)
-	sig := packets.NewSignature(sigInfo, valBytes)
-	packet.(*packets.Data).SetSignature(sig)
-	return tlvs[2:], nil
+	d := packet.(*packets.Data)
+	d.SetSignature(packets.NewSignature(sigInfo, d.GetSignature().GetsigVal()))
+	return nil
+}
+
+func decodeDataSignatureValue(packet interface{}, t Tlv, opts *DecodeOptions) error {
+	valBytes, _ := decodeSignatureValue(t)
+	d := packet.(*packets.Data)
+	d.SetSignature(packets.NewSignature(d.GetSignature().GetsigInfo(), valBytes))
+	return nil
 }
 
 func decodeSignatureValue(t Tlv) ([]byte, error) {
@@ -311,37 +587,73 @@ func decodeSignatureValue(t Tlv) ([]byte, error) {
 	return t.V, nil
 }
 
-func decodeSignatureInfo(t Tlv) (packets.SignatureInfo, error) {
-	tlvs, _ := ParseTlvsFromBytes(t.V)
-	sigTypeTlv := tlvs[0]
-	keyLocatorTlv := Tlv{}
-	//checking if the keyLocator is there
-	if len(tlvs) > 1 {
-		keyLocatorTlv = tlvs[1]
+//the fields of a signature info while they are decoded
+type signatureInfoFields struct {
+	sigType       uint64
+	keyLocator    packets.KeyLocator
+	hasKeyLocator bool
+}
+
+func decodeSignatureInfo(t Tlv, opts *DecodeOptions) (packets.SignatureInfo, error) {
+	fields := signatureInfoFields{}
+	if err := decodeFields(t, &fields, signatureInfoRules, opts); err != nil {
+		return packets.SignatureInfo{}, err
 	}
-	sigType, _ := decodeSignatureType(sigTypeTlv)
-	keyLocator, hasKeyLoc, _ := decodeKeyLocator(keyLocatorTlv)
-	result := packets.NewSignatureInfo(sigType, hasKeyLoc, keyLocator)
+	result := packets.NewSignatureInfo(fields.sigType, fields.hasKeyLocator, fields.keyLocator)
 	return result, nil
 }
 
+//the signature type and the key locator, the validity period is recognized but not kept for now
+func decodeSignatureInfoField(p interface{}, field Tlv, opts *DecodeOptions) error {
+	fields := p.(*signatureInfoFields)
+	switch field.T {
+	case SIGNATURE_TYPE:
+		x, err := decodeSignatureType(field)
+		if err != nil {
+			return err
+		}
+		fields.sigType = x
+	case KEY_LOCATOR:
+		x, ok, err := decodeKeyLocator(field, opts)
+		if err != nil {
+			return err
+		}
+		fields.keyLocator, fields.hasKeyLocator = x, ok
+	}
+	return nil
+}
+
 func decodeSignatureType(t Tlv) (uint64, error) {
 	if t.T != SIGNATURE_TYPE {
-		return 0, errors.New("DecodeSignatureValue : --- unexpected type ---")
+		return 0, fieldError(errors.New("DecodeSignatureType : --- unexpected type ---"), t)
 	}
-	sigType := DecodeNonNegativeInteger(t.V)
-	return sigType, nil
+	x, err := DecodeNonNegativeIntegerValue(t)
+	return x, fieldError(err, t)
 }
 
-func decodeKeyLocator(t Tlv) (packets.KeyLocator, bool, error) {
+func decodeKeyLocator(t Tlv, opts *DecodeOptions) (packets.KeyLocator, bool, error) {
 	if t.T != KEY_LOCATOR {
-		return packets.KeyLocator{}, false, errors.New("DecodeSignatureValue : --- unexpected type ---")
+		return packets.KeyLocator{}, false, fieldError(errors.New("DecodeKeyLocator : --- unexpected type ---"), t)
+	}
+	scratch := getTlvs()
+	defer putTlvs(scratch)
+	fields, err := opts.enter(scratch, t)
+	if err != nil {
+		return packets.KeyLocator{}, false, fieldError(err, t)
 	}
-	keyLocValueTlv, _, _, _ := TlvFromBytes(t.V)
+	defer opts.leave()
+	//a name or a digest and nothing after it, checked like Validate does
+	if _, _, err := singleField(t, NAME); err != nil {
+		return packets.KeyLocator{}, false, fieldError(err, t)
+	}
+	keyLocValueTlv := fields[0]
 	result := packets.KeyLocator{}
 	switch keyLocValueTlv.T {
 	case NAME:
-		nameRef, _ := decodeName(keyLocValueTlv)
+		nameRef, err := decodeName(keyLocValueTlv, opts)
+		if err != nil {
+			return packets.KeyLocator{}, false, fieldError(err, t)
+		}
 		//fmt.Printf("+++++ %v +++++", nameRef)
 		//fmt.Printf("+++++ %v +++++", keyLocValueTlv)
 		result = packets.KeyLocator{
@@ -358,14 +670,19 @@ func decodeKeyLocator(t Tlv) (packets.KeyLocator, bool, error) {
 			keyDigest,
 			true,
 		}
-
+	default:
+		if IsCritical(keyLocValueTlv.T) {
+			return packets.KeyLocator{}, false, fieldError(fieldError(&UnknownTypeError{Type: keyLocValueTlv.T, Parent: KEY_LOCATOR}, keyLocValueTlv), t)
+		}
+		//a key locator of a kind that is not known is ignored
+		return packets.KeyLocator{}, false, nil
 	}
 	return result, true, nil
 }
 
 func decodeKeyDigest(t Tlv) ([]byte, error) {
 	if t.T != KEY_DIGEST {
-		return nil, errors.New("DecodeSignatureValue : --- unexpected type ---")
+		return nil, errors.New("DecodeKeyDigest : --- unexpected type ---")
 	}
 	return t.V, nil
 }
@@ -1,101 +1,133 @@
 package tlv
 
 import (
-	"log"
 	"ndn-router/nfd/tlv/name"
 	"ndn-router/nfd/tlv/packets"
-//	"time"
 )
 
-
-
-//reads the input (bytes) swithes on the type and calls the appropriate decoder
-func ConcurrentDecode(packet []byte) packets.NdnPacket {
-	t, _, _, _ := TlvFromBytes(packet)
+//reads the input (bytes) swithes on the type and calls the appropriate decoder,
+//the errors are the ones of Decode
+func ConcurrentDecode(packet []byte) (packets.NdnPacket, error) {
+	t, err := (&DecodeOptions{}).readPacket(packet)
+	if err != nil {
+		return nil, err
+	}
 	switch t.T {
 	case INTEREST:
-		resultInterest, _ := concurrentDecodeInterest(t)
+		resultInterest, err := concurrentDecodeInterest(t)
+		if err != nil {
+			return nil, locate(err, packet)
+		}
 		resultInterest.Setbuffer(packet)
-		return resultInterest
+		return resultInterest, nil
 	case DATA:
-		resultData, _ := decodeData(t)
+		resultData, err := decodeData(t, &DecodeOptions{})
+		if err != nil {
+			return nil, locate(err, packet)
+		}
 		resultData.Setbuffer(packet)
-		return resultData
+		return resultData, nil
 	default:
-		log.Println("unknown bytes")
-		return nil
+		return nil, unknownPacketError(t.T)
 	}
 }
 
+//the error of a go routine with the index of the field it decoded
+type fieldResult struct {
+	at  int
+	err error
+}
+
 func concurrentDecodeInterest(t Tlv) (packets.Interest, error) {
-	tlvs, _ := ParseTlvsFromBytes(t.V)
+	//the fields are walked like decodeFields does, the name and the selectors are decoded
+	//by go routines, the others in place. the error reported is the one of the first field
+	//in error, like Decode, whichever go routine ends first
+	if err := checkInterestFormat(t); err != nil {
+		return packets.Interest{}, fieldError(err, t)
+	}
+	rules := interestRulesOf(t)
+	tlvs, err := ParseTlvsFromBytes(t.V)
+	if err != nil {
+		return packets.Interest{}, fieldError(err, t)
+	}
 	resultInterest := packets.Interest{}
-	//channels
-	chInterestName := make(chan name.Name)
-	chInterestSelectors := make(chan packets.Selectors) 
-	// chInterestNonce := make(chan [4]byte)
-	// chInterestLifeTime := make(chan time.Duration)
-	//launch go routines
-	for _, tlv := range tlvs {
-		switch (tlv.T){
-		case NAME : 
-			go concurrentDecodeInterestName(tlv, chInterestName)
-		case SELECTORS:
-			go concurrentDecodeInterestSelectors(tlv, chInterestSelectors)
-			
+	//channels, buffered so a go routine never blocks when another field failed
+	chInterestName := make(chan name.Name, 1)
+	chInterestSelectors := make(chan packets.Selectors, 1)
+	chErr := make(chan fieldResult, 2)
+	//launch go routines for the nested fields, stop the walk at the first error found in place
+	var first *fieldResult
+	launched := 0
+	k := newFieldTracker(rules, false)
+	for i, tlv := range tlvs {
+		r, err := k.add(tlv.T)
+		switch {
+		case err != nil:
+			first = &fieldResult{i, fieldError(err, tlv)}
+		case r < 0:
+			if IsCritical(tlv.T) {
+				first = &fieldResult{i, fieldError(&UnknownTypeError{Type: tlv.T, Parent: t.T}, tlv)}
+			}
+		case tlv.T == NAME:
+			go concurrentDecodeInterestName(i, tlv, chInterestName, chErr)
+			launched++
+		case tlv.T == SELECTORS:
+			go concurrentDecodeInterestSelectors(i, tlv, chInterestSelectors, chErr)
+			launched++
+		case rules[r].decode != nil:
+			if err := rules[r].decode(&resultInterest, tlv, &DecodeOptions{}); err != nil {
+				first = &fieldResult{i, fieldError(err, tlv)}
+			}
+		}
+		if first != nil {
+			break
 		}
 	}
-	// go concurrentDecodeInterestName(tlvs[0], chInterestName)
-	// go concurrentDecodeInterestSelectors(tlvs[1], chInterestSelectors)
-	
 
-	for i := 0; i < len(tlvs); i++ {
-        select {
-        case name := <-chInterestName:
+	for i := 0; i < launched; i++ {
+		select {
+		case name := <-chInterestName:
 			resultInterest.SetName(name)
-		case sel := <- chInterestSelectors:
+		case sel := <-chInterestSelectors:
 			resultInterest.Selector = sel
-        } 
+		case res := <-chErr:
+			if first == nil || res.at < first.at {
+				first = &res
+			}
+		}
+	}
+	if first != nil {
+		return packets.Interest{}, fieldError(first.err, t)
+	}
+	if typ, ok := k.missing(); ok {
+		return packets.Interest{}, fieldError(&TlvError{Type: typ, Offset: len(t.V), Err: ErrMissingField}, t)
 	}
-	
+	//like Decode, the digest is checked once the fields are decoded and the format is the detected one
+	if resultInterest.HasApplicationParameters || hasParametersDigest(resultInterest.GetName()) {
+		if err := checkParametersDigest(t); err != nil {
+			return packets.Interest{}, fieldError(err, t)
+		}
+	}
+	resultInterest.SetFormat(interestFormat(t))
 	return resultInterest, nil
 }
 
-func concurrentDecodeInterestName(tlv Tlv, chInterestName chan name.Name) {
+func concurrentDecodeInterestName(at int, tlv Tlv, chInterestName chan name.Name, chErr chan fieldResult) {
 	//decodeName is common to both interest and data
-	name, err := decodeName(tlv) //the name tlv is the first one tlvs[0]
+	name, err := decodeName(tlv, &DecodeOptions{})
 	if err != nil {
+		chErr <- fieldResult{at, fieldError(err, tlv)}
 		return
 	}
 	chInterestName <- name
 }
 
-func concurrentDecodeInterestSelectors(tlv Tlv, chInterestSelectors chan packets.Selectors) {
-	selectorFields, _ := ParseTlvsFromBytes(tlv.V) // from []bytes to []Tlv
-	sel := packets.Selectors{}
-	for _, field := range selectorFields {
-		switch field.T {
-			case MIN_SUFFIX_COMPONENTS:
-				x := DecodeNonNegativeInteger(field.V)
-				sel.SetMinSuffixComponents(x)
-			case MAX_SUFFIX_COMPONENTS:
-				x := DecodeNonNegativeInteger(field.V)
-				sel.SetMaxSuffixComponents(x)
-			case PUBLISHER_PUB_KEY_LOCATOR:
-				x, _, err := decodeKeyLocator(field)
-				if err == nil {
-					sel.SetPublisherPublicKeyLocator(x)
-				}
-			case EXCLUDE:
-				x, _ := decodeExclude(field)
-				sel.SetExclude(x)
-			case CHILD_SELECTOR:
-				x := DecodeNonNegativeInteger(field.V)
-				sel.SetChildSelector(x)
-			case MUST_BE_FRESH:
-				x := decodeMustBeFresh(field)
-				sel.SetMustBeFresh(x)
-		}
+func concurrentDecodeInterestSelectors(at int, tlv Tlv, chInterestSelectors chan packets.Selectors, chErr chan fieldResult) {
+	//the selectors are decoded the same way as in decodeInterest
+	holder := packets.Interest{}
+	if err := decodeInterestSelectors(&holder, tlv, &DecodeOptions{}); err != nil {
+		chErr <- fieldResult{at, fieldError(err, tlv)}
+		return
 	}
-	chInterestSelectors <- sel
+	chInterestSelectors <- holder.Selector
 }
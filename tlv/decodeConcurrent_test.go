@@ -0,0 +1,21 @@
+package tlv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConcurrentDecodeAgreesWithDecode(t *testing.T) {
+	for _, c := range corpus {
+		b := parseCorpus(t, c.text)
+		want, decodeErr := Decode(b)
+		got, err := ConcurrentDecode(b)
+		if (err == nil) != (decodeErr == nil) || err != nil && err.Error() != decodeErr.Error() {
+			t.Errorf("%s: ConcurrentDecode gives %v, Decode %v", c.name, err, decodeErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ConcurrentDecode gives %+v, Decode %+v", c.name, got, want)
+		}
+	}
+}
@@ -2,20 +2,33 @@ package tlv
 
 import (
 	"ndn-router/nfd/tlv/packets"
-	"log"
 )
-func DecodeOuterMostConcurrency(packet []byte, result chan packets.NdnPacket) {
-	t, _, _, _ := TlvFromBytes(packet)
+//meant to be run as a go routine, sends the decoded packet on result or
+//the error Decode would give back on errs
+func DecodeOuterMostConcurrency(packet []byte, result chan packets.NdnPacket, errs chan error) {
+	t, err := (&DecodeOptions{}).readPacket(packet)
+	if err != nil {
+		errs <- err
+		return
+	}
 	switch t.T {
 	case INTEREST:
-		resultInterest, _ := decodeInterest(t)
+		resultInterest, err := decodeInterest(t, &DecodeOptions{})
+		if err != nil {
+			errs <- locate(err, packet)
+			return
+		}
 		resultInterest.Setbuffer(packet)
 		result <- resultInterest
 	case DATA:
-		resultData, _ := decodeData(t)
+		resultData, err := decodeData(t, &DecodeOptions{})
+		if err != nil {
+			errs <- locate(err, packet)
+			return
+		}
 		resultData.Setbuffer(packet)
 		result <- resultData
 	default:
-		log.Println("unknown bytes")
+		errs <- unknownPacketError(t.T)
 	}
 }
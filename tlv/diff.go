@@ -0,0 +1,261 @@
+package tlv
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//DiffKind says how an element differs between the two encodings given to Diff
+type DiffKind int
+
+const (
+	Added   DiffKind = iota + 1 //only in the second encoding
+	Removed                     //only in the first encoding
+	Changed                     //in both, with a different value
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+func (k DiffKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+//Difference is one element that is not the same in the two encodings.
+//Path names the element from the outer tlv down, e.g. DATA/NAME/NAME_COMPONENT[1], the
+//index counting the siblings of the same type. A is the value in the first encoding
+//and B the one in the second, nil when the element is not there
+type Difference struct {
+	Kind  DiffKind `json:"kind"`
+	Path  string   `json:"path"`
+	Types []uint64 `json:"types"`
+	Type  uint64   `json:"type"`
+	A     []byte   `json:"-"`
+	B     []byte   `json:"-"`
+}
+
+//the values are given in hex in the json report
+func (d Difference) MarshalJSON() ([]byte, error) {
+	type plain Difference
+	return json.Marshal(struct {
+		plain
+		A string `json:"a,omitempty"`
+		B string `json:"b,omitempty"`
+	}{plain(d), hexValue(d.A), hexValue(d.B)})
+}
+
+func hexValue(v []byte) string {
+	if v == nil {
+		return ""
+	}
+	return hex.EncodeToString(v)
+}
+
+//DiffReport holds the differences found by Diff, in wire order
+type DiffReport struct {
+	Differences []Difference `json:"differences"`
+}
+
+//true when the two encodings have the same structure and values
+func (r *DiffReport) Equal() bool {
+	return len(r.Differences) == 0
+}
+
+//human readable report, one line per difference:
+//
+//	changed DATA/META_INFO/FRESHNESS_PERIOD (0x19): 03 e8 -> 07 d0
+//	added   DATA/NAME/NAME_COMPONENT[2] (0x8): 63
+func (r *DiffReport) String() string {
+	if r.Equal() {
+		return "no differences\n"
+	}
+	var sb strings.Builder
+	for _, d := range r.Differences {
+		fmt.Fprintf(&sb, "%-7s %s (0x%x): ", d.Kind, d.Path, d.Type)
+		switch d.Kind {
+		case Added:
+			fmt.Fprintf(&sb, "% x\n", d.B)
+		case Removed:
+			fmt.Fprintf(&sb, "% x\n", d.A)
+		default:
+			fmt.Fprintf(&sb, "% x -> % x\n", d.A, d.B)
+		}
+	}
+	return sb.String()
+}
+
+//the report as indented json
+func (r *DiffReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+//Diff parses the two encodings into element trees (see ParseElements) and reports the
+//elements added, removed and changed from a to b. the children of nested elements are
+//matched in order, with the same type, preferring the ones with the same value, so a
+//name component inserted in the middle of a name is reported as added instead of
+//changing all the components after it
+func Diff(a, b []byte) (*DiffReport, error) {
+	ea, err := ParseElements(a)
+	if err != nil {
+		return nil, fmt.Errorf("Diff : --- first encoding --- : %w", err)
+	}
+	eb, err := ParseElements(b)
+	if err != nil {
+		return nil, fmt.Errorf("Diff : --- second encoding --- : %w", err)
+	}
+	r := &DiffReport{Differences: []Difference{}}
+	r.diffLists(nil, nil, ea, eb)
+	return r, nil
+}
+
+//compares the children of the element at path (types holding the types along it)
+func (r *DiffReport) diffLists(path []string, types []uint64, a, b []*Element) {
+	pairs := matchElements(a, b)
+	for _, p := range pairs {
+		var e *Element
+		if p.a >= 0 {
+			e = a[p.a]
+		} else {
+			e = b[p.b]
+		}
+		step := TypeName(e.Type)
+		if countType(a, e.Type) > 1 || countType(b, e.Type) > 1 {
+			if p.a >= 0 {
+				step += fmt.Sprintf("[%d]", indexOfType(a, p.a))
+			} else {
+				step += fmt.Sprintf("[%d]", indexOfType(b, p.b))
+			}
+		}
+		elPath := append(path[:len(path):len(path)], step)
+		elTypes := append(types[:len(types):len(types)], e.Type)
+		switch {
+		case p.b < 0:
+			r.add(Removed, elPath, elTypes, elementValue(a[p.a]), nil)
+		case p.a < 0:
+			r.add(Added, elPath, elTypes, nil, elementValue(b[p.b]))
+		case a[p.a].IsNested() && b[p.b].IsNested():
+			r.diffLists(elPath, elTypes, a[p.a].Children, b[p.b].Children)
+		default:
+			va, vb := elementValue(a[p.a]), elementValue(b[p.b])
+			if string(va) != string(vb) {
+				r.add(Changed, elPath, elTypes, va, vb)
+			}
+		}
+	}
+}
+
+func (r *DiffReport) add(kind DiffKind, path []string, types []uint64, a, b []byte) {
+	r.Differences = append(r.Differences, Difference{
+		Kind:  kind,
+		Path:  strings.Join(path, "/"),
+		Types: types,
+		Type:  types[len(types)-1],
+		A:     a,
+		B:     b,
+	})
+}
+
+//the value of the element as it is on the wire
+func elementValue(e *Element) []byte {
+	if !e.IsNested() {
+		return e.Value
+	}
+	b := e.Encode()
+	return b[len(b)-e.Length():]
+}
+
+func countType(l []*Element, t uint64) int {
+	n := 0
+	for _, e := range l {
+		if e.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+//index of l[i] among the elements of l with its type
+func indexOfType(l []*Element, i int) int {
+	n := 0
+	for _, e := range l[:i] {
+		if e.Type == l[i].Type {
+			n++
+		}
+	}
+	return n
+}
+
+//an element of a matched with an element of b, -1 on the side where it is missing
+type elementPair struct {
+	a, b int
+}
+
+//aligns the two lists like a longest common subsequence where elements of the same type
+//can be matched, a match with the same encoding counting more than one with another value
+func matchElements(a, b []*Element) []elementPair {
+	encA, encB := make([]string, len(a)), make([]string, len(b))
+	for i, e := range a {
+		encA[i] = string(e.Encode())
+	}
+	for j, e := range b {
+		encB[j] = string(e.Encode())
+	}
+	score := func(i, j int) int {
+		switch {
+		case a[i].Type != b[j].Type:
+			return 0
+		case encA[i] == encB[j]:
+			return 2
+		}
+		return 1
+	}
+	//best[i][j] is the best score for a[i:] and b[j:]
+	best := make([][]int, len(a)+1)
+	for i := range best {
+		best[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			best[i][j] = best[i+1][j]
+			if best[i][j+1] > best[i][j] {
+				best[i][j] = best[i][j+1]
+			}
+			if s := score(i, j); s > 0 && best[i+1][j+1]+s > best[i][j] {
+				best[i][j] = best[i+1][j+1] + s
+			}
+		}
+	}
+	pairs := []elementPair{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch s := score(i, j); {
+		case s > 0 && best[i][j] == best[i+1][j+1]+s:
+			pairs = append(pairs, elementPair{i, j})
+			i, j = i+1, j+1
+		case best[i][j] == best[i+1][j]:
+			pairs = append(pairs, elementPair{i, -1})
+			i++
+		default:
+			pairs = append(pairs, elementPair{-1, j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		pairs = append(pairs, elementPair{i, -1})
+	}
+	for ; j < len(b); j++ {
+		pairs = append(pairs, elementPair{-1, j})
+	}
+	return pairs
+}
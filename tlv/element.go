@@ -0,0 +1,225 @@
+package tlv
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//Element is a generic tlv tree, it can hold any packet, including the ones
+//that have no type in the packets package (management responses, link protocol headers ...)
+//a nested element has Children and no Value, a leaf has a Value and no Children
+type Element struct {
+	Type     uint64
+	Value    []byte
+	Children []*Element
+}
+
+//returned by a Walk visitor to skip the children of the current element
+var SkipChildren = errors.New("tlv: skip children")
+
+//creates a leaf element
+func NewElement(t uint64, value []byte) *Element {
+	return &Element{Type: t, Value: value}
+}
+
+//creates a nested element
+func NewNestedElement(t uint64, children ...*Element) *Element {
+	if children == nil {
+		children = []*Element{}
+	}
+	return &Element{Type: t, Children: children}
+}
+
+//parses the single tlv held by b into a tree, trailing bytes are an error.
+//values of the KindNested types of the registry are parsed recursively, the ones of the other
+//registered types are leaves, values of unknown types are parsed when they are made of
+//valid tlvs and kept as leaves otherwise.
+//the tree cannot be deeper than DefaultMaxDepth.
+//leaf values point into b
+func ParseElement(b []byte) (*Element, error) {
+	t, err, ts, ls := TlvFromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return nil, errors.New("ParseElement : --- trailing bytes after the tlv ---")
+	}
+	return elementFromTlv(t, ts+ls, false, 0)
+}
+
+//parses a list of tlvs into trees, like ParseTlvsFromBytes does for flat tlvs
+func ParseElements(b []byte) ([]*Element, error) {
+	return parseElements(b, false, 0)
+}
+
+//guess is set while parsing the value of an unknown type, errors are then not reported
+//to the caller, the value is kept as a leaf instead. depth is the level of the parsed elements
+func parseElements(b []byte, guess bool, depth int) ([]*Element, error) {
+	result := []*Element{}
+	for i := 0; i < len(b); {
+		t, err, ts, ls := TlvFromBytes(b[i:])
+		if err != nil {
+			return nil, errorAt(err, i)
+		}
+		e, err := elementFromTlv(t, ts+ls, guess, depth)
+		if err != nil {
+			return nil, errorAt(err, i)
+		}
+		result = append(result, e)
+		i += ts + ls + int(t.L)
+	}
+	return result, nil
+}
+
+//headerSize is the number of bytes taken by the type and length, used for error offsets
+func elementFromTlv(t Tlv, headerSize int, guess bool, depth int) (*Element, error) {
+	info, known := LookupType(t.T)
+	switch {
+	case known && info.Kind == KindNested && depth >= DefaultMaxDepth:
+		return nil, &TlvError{Type: t.T, Offset: 0, Err: ErrTooDeep}
+	case known && info.Kind == KindNested:
+		children, err := parseElements(t.V, guess, depth+1)
+		if err != nil {
+			return nil, errorAt(err, headerSize)
+		}
+		return &Element{Type: t.T, Children: children}, nil
+	case known || len(t.V) == 0 || depth >= DefaultMaxDepth:
+		return &Element{Type: t.T, Value: t.V}, nil
+	default:
+		children, err := parseElements(t.V, true, depth+1)
+		if err != nil {
+			return &Element{Type: t.T, Value: t.V}, nil
+		}
+		return &Element{Type: t.T, Children: children}, nil
+	}
+}
+
+//the name of the type of the element in the registry, the type in hex if it is not registered
+func (e *Element) Name() string {
+	return TypeName(e.Type)
+}
+
+//true when the element holds other elements
+func (e *Element) IsNested() bool {
+	return e.Children != nil
+}
+
+//returns the first child of type t, nil if there is none
+func (e *Element) Find(t uint64) *Element {
+	for _, c := range e.Children {
+		if c.Type == t {
+			return c
+		}
+	}
+	return nil
+}
+
+//returns all the children of type t
+func (e *Element) FindAll(t uint64) []*Element {
+	result := []*Element{}
+	for _, c := range e.Children {
+		if c.Type == t {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+//calls fn for e and all its descendants, parents first and in wire order.
+//parents holds the ancestors of the visited element, starting with e, and is only
+//valid during the call.
+//if fn returns SkipChildren the children of that element are not visited,
+//any other error stops the walk and is returned
+func (e *Element) Walk(fn func(el *Element, parents []*Element) error) error {
+	return e.walk(fn, nil)
+}
+
+func (e *Element) walk(fn func(el *Element, parents []*Element) error, parents []*Element) error {
+	err := fn(e, parents)
+	if err == SkipChildren {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	parents = append(parents, e)
+	for _, c := range e.Children {
+		if err := c.walk(fn, parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//returns the elements matching a path of types separated by '/', the first
+//type being the type of e, e.g. "0x06/0x16/0x1c" gives the key locator of a data packet.
+//types can be written in hex or decimal, '*' matches any type
+func (e *Element) Query(path string) ([]*Element, error) {
+	steps := strings.Split(strings.Trim(path, "/"), "/")
+	types := make([]uint64, len(steps))
+	for i, s := range steps {
+		if s == "*" {
+			types[i] = 0 //type 0 is reserved, it is used as the wildcard
+			continue
+		}
+		t, err := strconv.ParseUint(s, 0, 64)
+		if err != nil || t == 0 {
+			return nil, errors.New("Query : --- bad path element " + strconv.Quote(s) + " ---")
+		}
+		types[i] = t
+	}
+	current := []*Element{}
+	if types[0] == 0 || types[0] == e.Type {
+		current = append(current, e)
+	}
+	for _, t := range types[1:] {
+		next := []*Element{}
+		for _, el := range current {
+			for _, c := range el.Children {
+				if t == 0 || c.Type == t {
+					next = append(next, c)
+				}
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+//length of the value of the element, computed from the children for a nested element
+func (e *Element) Length() int {
+	if !e.IsNested() {
+		return len(e.Value)
+	}
+	l := 0
+	for _, c := range e.Children {
+		l += c.Size()
+	}
+	return l
+}
+
+//size of the whole element on the wire
+func (e *Element) Size() int {
+	return tlvSize(e.Type, e.Length())
+}
+
+//gives back the wire encoding of the element, reflecting any change made to the tree
+func (e *Element) Encode() []byte {
+	b := make([]byte, e.Size())
+	e.EncodeTo(b)
+	return b
+}
+
+//writes the element at the start of b, which must hold at least e.Size() bytes,
+//and gives back the number of bytes written
+func (e *Element) EncodeTo(b []byte) int {
+	if !e.IsNested() {
+		return writeTlv(b, e.Type, e.Value)
+	}
+	n := writeHeader(b, e.Type, e.Length())
+	for _, c := range e.Children {
+		n += c.EncodeTo(b[n:])
+	}
+	return n
+}
@@ -1,372 +1,538 @@
 package tlv
 
 import (
-	"bytes"
+	"crypto/sha256"
 	"errors"
 	"io"
-	"log"
+	"net"
 
 	"ndn-router/nfd/tlv/name"
 	"ndn-router/nfd/tlv/packets"
 )
 
-//reads an NDNpacket and writes a stream of bytes to the writer
+//encoding is done in two passes:
+//  - the ...Length functions compute the length of the value of every element
+//  - the write... functions then write the whole packet once into a single []byte
+//    of the right size, nothing is copied from one nesting level to the next
+//every write function writes at the start of b and gives back the number of bytes written
+
+//reads an NdnPacket and writes a stream of bytes to the writer
 //provided as a second parameter
 func Encode(packet packets.NdnPacket, byteStream io.Writer) error {
-	t := Tlv{}
-	switch packet.PacketType() {
-	case INTEREST:
-		t, _ = encodeInterest(packet.(packets.Interest))
-	case DATA:
-		t, _ = encodeData(packet.(packets.Data))
-	default:
-		return errors.New("Encode: -- unknown packet type --")
+	//the bytes only live until they are written, a pooled buffer is enough
+	buf, err := EncodePooled(packet)
+	if err != nil {
+		return err
 	}
-
-	//write the tlv as bytes
-	err := TlvToBytes(t, byteStream)
+	_, err = byteStream.Write(buf.Bytes())
+	buf.Release()
 	return err
 }
 
-func encodeInterest(i packets.Interest) (Tlv, error) {
-	//this returns a slice of Tlvs representing the value of the outer most Tlv
-	val, err := encodeSubTlvs(
-		i,
-		encodeInterestName,
-		encodeInterestSelectors,
-		encodeInterestNonce,
-		encodeInterestLifeTime,
-	)
+//EncodeOptions changes the way packets are encoded, the zero value encodes like Encode
+type EncodeOptions struct {
+	//the format the interests are written in, an interest of the other format is translated
+	//with its ToFormat method. zero writes each interest in its own format (GetFormat), which
+	//is the one it was decoded from
+	InterestFormat packets.InterestFormat
+}
 
-	if err != nil {
-		return Tlv{}, err
-	}
+//encodes like Encode, with the options
+func EncodeWithOptions(packet packets.NdnPacket, byteStream io.Writer, opts EncodeOptions) error {
+	return Encode(opts.apply(packet), byteStream)
+}
 
-	var b bytes.Buffer
-	TlvsToBytes(val, &b)
-	result := Tlv{
-		T: INTEREST,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
+//the packet as it is encoded with the options
+func (o EncodeOptions) apply(packet packets.NdnPacket) packets.NdnPacket {
+	if o.InterestFormat == packets.InterestFormatUnset {
+		return packet
+	}
+	switch p := packet.(type) {
+	case packets.Interest:
+		return p.ToFormat(o.InterestFormat)
+	case *packets.Interest:
+		i := p.ToFormat(o.InterestFormat)
+		return &i
 	}
-	return result, nil
+	return packet
 }
 
-func encodeData(d packets.Data) (Tlv, error) {
-	//this returns a slice of Tlvs representing the value of the outer most Tlv
-	val, err := encodeSubTlvs(
-		d,
-		encodeDataName,
-		encodeDataMetaInfo,
-		encodeDataContent,
-		encodeDataSignature,
-	)
+//encodes the packet into a new slice of exactly EncodedLength(packet) bytes
+func EncodeToBytes(packet packets.NdnPacket) ([]byte, error) {
+	size, err := EncodedLength(packet)
 	if err != nil {
-		return Tlv{}, err
+		return nil, err
 	}
-
-	var b bytes.Buffer
-	TlvsToBytes(val, &b)
-	result := Tlv{
-		T: DATA,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
+	b := make([]byte, size)
+	_, err = EncodeTo(packet, b)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return b, nil
 }
 
-func encodeSubTlvs(packet interface{}, enc ...encoder) ([]Tlv, error) {
-	//create a buffer here then get thte bytes back
-	var t []Tlv
-	var err error
-	for _, e := range enc {
-		t, err = e(packet, t)
+//returns the number of bytes the packet takes on the wire (outer type and length included)
+//can be used to size buffers or to check the MTU before encoding
+func EncodedLength(packet packets.NdnPacket) (int, error) {
+	switch packet.PacketType() {
+	case INTEREST:
+		i, err := asInterest(packet)
 		if err != nil {
-			return t, err
+			return 0, err
 		}
+		return tlvSize(INTEREST, interestLength(i)), nil
+	case DATA:
+		d, err := asData(packet)
+		if err != nil {
+			return 0, err
+		}
+		return tlvSize(DATA, dataLength(d)), nil
+	default:
+		return 0, errors.New("Encode: -- unknown packet type --")
 	}
-	return t, nil
 }
 
-//an encode is any function of this format
-type encoder func(packet interface{}, t []Tlv) ([]Tlv, error)
-
-func encodeInterestName(packet interface{}, t []Tlv) ([]Tlv, error) {
-	name := packet.(packets.Interest).GetName()
-	if name.Size() == 0 {
-		return nil, errors.New("Encode: -- a packet must have a name --")
+//writes the packet at the start of b, which must hold at least EncodedLength(packet) bytes
+//and gives back the number of bytes written
+func EncodeTo(packet packets.NdnPacket, b []byte) (int, error) {
+	size, err := EncodedLength(packet)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) < size {
+		return 0, io.ErrShortBuffer
+	}
+	switch packet.PacketType() {
+	case INTEREST:
+		i, _ := asInterest(packet)
+		return writeInterest(b, i), nil
+	default:
+		d, _ := asData(packet)
+		return writeData(b, d), nil
 	}
-	return append(t, encodeName(name)), nil
 }
 
-func encodeDataName(packet interface{}, t []Tlv) ([]Tlv, error) {
-	name := packet.(packets.Data).GetName()
-	if name.Size() == 0 {
-		return nil, errors.New("Encode: -- a packet must have a name --")
+//encodes the packet for a scatter-gather write: the Content of a data is not copied,
+//the result holds the bytes before it, the Content slice itself and the bytes after it,
+//so writing it with WriteTo on a net.Conn hands the payload to the kernel with writev.
+//an interest has no large field and is encoded as a single buffer.
+//the Content must not be modified until the buffers are written
+func EncodeBuffers(packet packets.NdnPacket) (net.Buffers, error) {
+	size, err := EncodedLength(packet)
+	if err != nil {
+		return nil, err
 	}
-	return append(t, encodeName(name)), nil
+	if packet.PacketType() == INTEREST {
+		i, _ := asInterest(packet)
+		b := make([]byte, size)
+		writeInterest(b, i)
+		return net.Buffers{b}, nil
+	}
+	d, _ := asData(packet)
+	content := d.GetContent()
+	//everything but the content goes in a single slice, split around the content
+	b := make([]byte, size-len(content))
+	n := writeDataHead(b, d)
+	tail := writeDataTail(b[n:], d)
+	if len(content) == 0 {
+		return net.Buffers{b[:n+tail]}, nil
+	}
+	return net.Buffers{b[:n:n], content, b[n : n+tail]}, nil
 }
 
-//encoding a name ==> goes back to encoding nae components
-func encodeName(n name.Name) Tlv {
-	t := []Tlv{}
-	for _, comp := range n {
-		t = append(t, encodeNameComponent(comp))
-	}
-	//need to convert []Tlv to []byte which will be the value of the name
-	var b bytes.Buffer
-	TlvsToBytes(t, &b)
-	result := Tlv{
-		T: NAME,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
-	}
-	return result
-}
-
-func encodeNameComponent(comp name.Component) Tlv {
-	b := comp.ComponentToBytes()
-	if comp == name.Any {
-		t := Tlv{
-			T: ANY,
-			L: uint64(len(b)),
-			V: b,
+//the packet can be given as a value or as a pointer (NewInterest gives back a pointer)
+//checks the fields the encoding cannot do without
+func asInterest(packet packets.NdnPacket) (packets.Interest, error) {
+	var i packets.Interest
+	switch p := packet.(type) {
+	case packets.Interest:
+		i = p
+	case *packets.Interest:
+		i = *p
+	default:
+		return i, errors.New("Encode: -- unknown packet type --")
+	}
+	if i.GetName().Size() == 0 {
+		return i, errors.New("Encode: -- a packet must have a name --")
+	}
+	if i.GetFormat() == packets.InterestFormatV02 && i.HasApplicationParameters {
+		return i, errors.New("Encode: -- ApplicationParameters can not be written in the format v0.2 --")
+	}
+	if err := checkName(i.GetName()); err != nil {
+		return i, err
+	}
+	for _, hint := range i.GetForwardingHint() {
+		if err := checkName(hint); err != nil {
+			return i, err
 		}
-		return t
 	}
-	t := Tlv{
-		T: NAME_COMPONENT,
-		L: uint64(len(b)),
-		V: b,
+	if i.Selector.HasPublisherPublicKeyLocator {
+		if err := checkKeyLocator(i.Selector.GetPublisherPublicKeyLocator()); err != nil {
+			return i, err
+		}
 	}
-	return t
+	return i, nil
 }
 
-func encodeInterestSelectors(packet interface{}, t []Tlv) ([]Tlv, error) {
-	sel := packet.(packets.Interest).Selector
-	val := []Tlv{}
-	var b bytes.Buffer
-
-	if sel.IsEmpty() {
-		//packet has no selectors ==> skip
-		return t, nil
+func asData(packet packets.NdnPacket) (packets.Data, error) {
+	var d packets.Data
+	switch p := packet.(type) {
+	case packets.Data:
+		d = p
+	case *packets.Data:
+		d = *p
+	default:
+		return d, errors.New("Encode: -- unknown packet type --")
 	}
-
-	if sel.HasMinSuffixComponents {
-		minSc := sel.GetMinSuffixComponents()
-		minScToByte := EncodeNonNegativeInteger(uint64(minSc))
-		x := Tlv{T: MIN_SUFFIX_COMPONENTS, L: uint64(len(minScToByte)), V: minScToByte}
-		val = append(val, x)
+	if d.GetName().Size() == 0 {
+		return d, errors.New("Encode: -- a packet must have a name --")
 	}
-
-	if sel.HasMaxSuffixComponents {
-		maxSc := sel.GetMaxSuffixComponents()
-		maxScToByte := EncodeNonNegativeInteger(uint64(maxSc))
-		x := Tlv{T: MAX_SUFFIX_COMPONENTS, L: uint64(len(maxScToByte)), V: maxScToByte}
-		val = append(val, x)
+	if err := checkName(d.GetName()); err != nil {
+		return d, err
 	}
+	return d, checkKeyLocator(d.GetSignature().GetsigInfo().GetKeyLocator())
+}
 
-	if sel.HasPublisherPublicKeyLocator {
-		pubKey := sel.GetPublisherPublicKeyLocator()
-		keyLoc := encodeKeyLocator(pubKey)
-		err := TlvToBytes(keyLoc, &b)
-		if err != nil {
-			return nil, err
+//size of a whole tlv (type, length and value) with a value of l bytes
+func tlvSize(t uint64, l int) int {
+	return varSize(t) + varSize(uint64(l)) + l
+}
+
+//writes the type and the length of a tlv
+func writeHeader(b []byte, t uint64, l int) int {
+	n := varEncoding(t, b)
+	return n + varEncoding(uint64(l), b[n:])
+}
+
+//writes a whole tlv with v as value
+func writeTlv(b []byte, t uint64, v []byte) int {
+	n := writeHeader(b, t, len(v))
+	return n + copy(b[n:], v)
+}
+
+func nonNegativeIntegerTlvSize(t uint64, x uint64) int {
+	return tlvSize(t, nonNegativeIntegerSize(x))
+}
+
+func writeNonNegativeIntegerTlv(b []byte, t uint64, x uint64) int {
+	n := writeHeader(b, t, nonNegativeIntegerSize(x))
+	return n + putNonNegativeInteger(b[n:], x)
+}
+
+//the interest is written in its format (GetFormat), the fields of the other format are left out
+//v0.2: Interest ::= INTEREST-TYPE TLV-LENGTH Name Selectors Nonce InterestLifetime?
+//v0.3: Interest ::= INTEREST-TYPE TLV-LENGTH Name CanBePrefix? MustBeFresh? ForwardingHint?
+//                   Nonce InterestLifetime? HopLimit? ApplicationParameters?
+//the name gets the ParametersSha256DigestComponent of the parameters (see params.go)
+func interestLength(i packets.Interest) int {
+	l := tlvSize(NAME, interestNameLength(i))
+	if i.GetFormat() == packets.InterestFormatV02 {
+		//the Selectors are what makes the interest v0.2 (see interestFormat), they are written even empty
+		l += tlvSize(SELECTORS, selectorsLength(i.Selector))
+		l += tlvSize(NONCE, len(i.GetNonce()))
+		if lt := i.GetInterestLifetime(); lt != -1 {
+			l += nonNegativeIntegerTlvSize(INTEREST_LIFETIME, uint64(lt))
 		}
-		x := Tlv{T: PUBLISHER_PUB_KEY_LOCATOR, L: uint64(b.Len()), V: b.Next(b.Len())}
-		val = append(val, x)
+		return l
+	}
+	if i.GetCanBePrefix() {
+		l += tlvSize(CAN_BE_PREFIX, 0)
+	}
+	if i.GetMustBeFresh() {
+		l += tlvSize(MUST_BE_FRESH, 0)
+	}
+	if hint := i.GetForwardingHint(); len(hint) > 0 {
+		l += tlvSize(FORWARDING_HINT, forwardingHintLength(hint))
+	}
+	l += tlvSize(NONCE, len(i.GetNonce()))
+	//this case should not occur but just in case it does
+	if lt := i.GetInterestLifetime(); lt != -1 {
+		l += nonNegativeIntegerTlvSize(INTEREST_LIFETIME, uint64(lt))
 	}
+	if i.HasHopLimit {
+		l += tlvSize(HOP_LIMIT, 1)
+	}
+	if i.HasApplicationParameters {
+		l += tlvSize(APPLICATION_PARAMETERS, len(i.GetApplicationParameters()))
+	}
+	return l
+}
 
-	if sel.HasExclude {
-		ex := sel.GetExclude()
-		val = append(val, encodeExclude(ex))
+func writeInterest(b []byte, i packets.Interest) int {
+	n := writeHeader(b, INTEREST, interestLength(i))
+	nameLen, digestAt := writeInterestName(b[n:], i)
+	digestAt += n //only used with parameters, it is never -1 then
+	n += nameLen
+	if i.GetFormat() == packets.InterestFormatV02 {
+		n += writeSelectors(b[n:], i.Selector)
+		nonce := i.GetNonce()
+		n += writeTlv(b[n:], NONCE, nonce[:])
+		if lt := i.GetInterestLifetime(); lt != -1 {
+			n += writeNonNegativeIntegerTlv(b[n:], INTEREST_LIFETIME, uint64(lt))
+		}
+		return n
+	}
+	if i.GetCanBePrefix() {
+		n += writeHeader(b[n:], CAN_BE_PREFIX, 0)
+	}
+	if i.GetMustBeFresh() {
+		n += writeHeader(b[n:], MUST_BE_FRESH, 0)
+	}
+	if hint := i.GetForwardingHint(); len(hint) > 0 {
+		n += writeForwardingHint(b[n:], hint)
+	}
+	nonce := i.GetNonce()
+	n += writeTlv(b[n:], NONCE, nonce[:])
+	if lt := i.GetInterestLifetime(); lt != -1 {
+		n += writeNonNegativeIntegerTlv(b[n:], INTEREST_LIFETIME, uint64(lt))
 	}
+	if i.HasHopLimit {
+		n += writeHeader(b[n:], HOP_LIMIT, 1)
+		b[n] = i.GetHopLimit()
+		n++
+	}
+	if i.HasApplicationParameters {
+		//the parameters are the last field, the digest covers them up to the end
+		paramsAt := n
+		n += writeTlv(b[n:], APPLICATION_PARAMETERS, i.GetApplicationParameters())
+		sum := sha256.Sum256(b[paramsAt:n])
+		copy(b[digestAt:], sum[:])
+	}
+	return n
+}
 
-	if sel.HasChildSelector {
-		chSel := sel.GetChildSelector()
-		chSelToBytes := EncodeNonNegativeInteger(uint64(chSel))
-		x := Tlv{T: CHILD_SELECTOR, L: uint64(len(chSelToBytes)), V: chSelToBytes}
-		val = append(val, x)
+// ForwardingHint ::= FORWARDING-HINT-TYPE TLV-LENGTH 1*Name
+func forwardingHintLength(hint []name.Name) int {
+	l := 0
+	for _, nm := range hint {
+		l += tlvSize(NAME, nameLength(nm))
 	}
+	return l
+}
 
-	if sel.GetMustBeFresh() == true {
-		x := Tlv{T: MUST_BE_FRESH, L: 0, V: nil}
-		val = append(val, x)
+func writeForwardingHint(b []byte, hint []name.Name) int {
+	n := writeHeader(b, FORWARDING_HINT, forwardingHintLength(hint))
+	for _, nm := range hint {
+		n += writeName(b[n:], nm)
 	}
+	return n
+}
 
-	err := TlvsToBytes(val, &b)
-	if err != nil {
-		return nil, err
+//Data ::= DATA-TLV TLV-LENGTH Name MetaInfo Content Signature
+func dataLength(d packets.Data) int {
+	sig := d.GetSignature()
+	l := tlvSize(NAME, nameLength(d.GetName()))
+	l += tlvSize(META_INFO, metaInfoLength(d.GetMetaInfo()))
+	l += tlvSize(CONTENT, len(d.GetContent()))
+	l += tlvSize(SIGNATURE_INFO, signatureInfoLength(sig.GetsigInfo()))
+	l += tlvSize(SIGNATURE_VALUE, len(sig.GetsigVal()))
+	return l
+}
+
+func writeData(b []byte, d packets.Data) int {
+	n := writeDataHead(b, d)
+	n += copy(b[n:], d.GetContent())
+	return n + writeDataTail(b[n:], d)
+}
+
+//writes the data up to the value of the content (header of the content included)
+func writeDataHead(b []byte, d packets.Data) int {
+	n := writeHeader(b, DATA, dataLength(d))
+	n += writeName(b[n:], d.GetName())
+	n += writeMetaInfo(b[n:], d.GetMetaInfo())
+	return n + writeHeader(b[n:], CONTENT, len(d.GetContent()))
+}
+
+//writes what follows the content
+func writeDataTail(b []byte, d packets.Data) int {
+	sig := d.GetSignature()
+	n := writeSignatureInfo(b, sig.GetsigInfo())
+	return n + writeTlv(b[n:], SIGNATURE_VALUE, sig.GetsigVal())
+}
+
+//a name is a list of name components
+func nameLength(n name.Name) int {
+	l := 0
+	for _, comp := range n {
+		l += componentSize(comp)
 	}
-	result := Tlv{T: SELECTORS, L: uint64(b.Len()), V: b.Next(b.Len())}
-	return append(t, result), nil
+	return l
 }
 
-func encodeExclude(ex name.Exclude) Tlv {
-	v := []Tlv{}
-	for _, c := range ex {
-		v = append(v, encodeNameComponent(c))
+func writeName(b []byte, nm name.Name) int {
+	n := writeHeader(b, NAME, nameLength(nm))
+	for _, comp := range nm {
+		n += writeNameComponent(b[n:], comp)
 	}
+	return n
+}
 
-	var b bytes.Buffer
-	TlvsToBytes(v, &b)
-	result := Tlv{
-		T: EXCLUDE,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
+//the type of the tlv of a component, ANY for the name.Any of the excludes.
+//a name can not hold Any, checkName rejects it before anything is written
+func componentType(comp name.Component) uint64 {
+	return comp.GetType()
+}
+
+//the names of a packet can hold any component but the Any of the excludes
+func checkName(nm name.Name) error {
+	for _, comp := range nm {
+		if comp.Type == name.AnyType {
+			return errors.New("Encode: -- Any can only be used in an Exclude --")
+		}
 	}
-	return result
+	return nil
 }
 
-func encodeInterestNonce(packet interface{}, t []Tlv) ([]Tlv, error) {
-	n := packet.(packets.Interest).GetNonce()
-	nonce := Tlv{
-		T: NONCE,
-		L: uint64(len(n)),
-		V: n[:],
+//checks the name of a key locator, if it has one
+func checkKeyLocator(keyLoc packets.KeyLocator) error {
+	if !keyLoc.HasName {
+		return nil
 	}
-	return append(t, nonce), nil
+	return checkName(keyLoc.Name)
+}
+
+//size of the whole component tlv
+func componentSize(comp name.Component) int {
+	return tlvSize(componentType(comp), len(comp.Value))
 }
 
-func encodeInterestLifeTime(packet interface{}, t []Tlv) ([]Tlv, error) {
-	lt := packet.(packets.Interest).GetInterestLifetime()
-	lifeTime := Tlv{
-		T: INTEREST_LIFETIME,
+func writeNameComponent(b []byte, comp name.Component) int {
+	n := writeHeader(b, componentType(comp), len(comp.Value))
+	return n + copy(b[n:], comp.Value)
+}
+
+// Selectors ::= SELECTORS-TYPE TLV-LENGTH
+//                 MinSuffixComponents? MaxSuffixComponents? PublisherPublicKeyLocator?
+//                 Exclude? ChildSelector? MustBeFresh?
+func selectorsLength(sel packets.Selectors) int {
+	l := 0
+	if sel.HasMinSuffixComponents {
+		l += nonNegativeIntegerTlvSize(MIN_SUFFIX_COMPONENTS, sel.GetMinSuffixComponents())
 	}
-	//this case should not occur but just in case it does
-	if int64(lt) == -1 {
-		return t, nil
-		// lifeTime.L = 2                  // 4000 Millisecond is 0x0FA0 ==> 2 bytes
-		// lifeTime.V = []byte{0x0F, 0xA0} // 4 Seconds
-	} else {
-		//need to convert lt to []byte
-		b := EncodeNonNegativeInteger(uint64(lt))
-		lifeTime.L = uint64(len(b))
-		lifeTime.V = b
-	}
-	return append(t, lifeTime), nil
-}
-
-func encodeDataMetaInfo(packet interface{}, t []Tlv) ([]Tlv, error) {
-	mi := packet.(packets.Data).GetMetaInfo()
-	val := []Tlv{}
-	if ct := mi.GetContentType(); ct != packets.Unknown {
-		ctToByte := EncodeNonNegativeInteger(uint64(ct))
-		x := Tlv{T: CONTENT_TYPE, L: uint64(len(ctToByte)), V: ctToByte}
-		val = append(val, x)
+	if sel.HasMaxSuffixComponents {
+		l += nonNegativeIntegerTlvSize(MAX_SUFFIX_COMPONENTS, sel.GetMaxSuffixComponents())
 	}
-	if fp := mi.GetFreshnessPeriod(); fp != -1 {
-		fpToByte := EncodeNonNegativeInteger(uint64(fp))
-		x := Tlv{T: FRESHNESS_PERIOD, L: uint64(len(fpToByte)), V: fpToByte}
-		val = append(val, x)
+	if sel.HasPublisherPublicKeyLocator {
+		l += tlvSize(PUBLISHER_PUB_KEY_LOCATOR, keyLocatorSize(sel.GetPublisherPublicKeyLocator()))
 	}
-	if id := mi.GetFinalBlockID(); len(id.GetValue()) > 0 {
-		idToByte := id.ComponentToBytes()
-		x := Tlv{T: CONTENT_TYPE, L: uint64(len(idToByte)), V: idToByte}
-		val = append(val, x)
+	if sel.HasExclude {
+		l += tlvSize(EXCLUDE, excludeLength(sel.GetExclude()))
 	}
-
-	var b bytes.Buffer
-	err := TlvsToBytes(val, &b)
-	if err != nil {
-		return t, err
+	if sel.HasChildSelector {
+		l += nonNegativeIntegerTlvSize(CHILD_SELECTOR, sel.GetChildSelector())
 	}
-	metaInfo := Tlv{
-		T: META_INFO,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
+	if sel.GetMustBeFresh() {
+		l += tlvSize(MUST_BE_FRESH, 0)
 	}
-	return append(t, metaInfo), nil
+	return l
 }
 
-func encodeDataContent(packet interface{}, t []Tlv) ([]Tlv, error) {
-	ct := packet.(packets.Data).GetContent()
-	log.Printf("@@@@@@@@@@@@@@ %v", ct)
-	x := Tlv{
-		T: CONTENT,
-		L: uint64(len(ct)),
-		V: ct,
+func writeSelectors(b []byte, sel packets.Selectors) int {
+	n := writeHeader(b, SELECTORS, selectorsLength(sel))
+	if sel.HasMinSuffixComponents {
+		n += writeNonNegativeIntegerTlv(b[n:], MIN_SUFFIX_COMPONENTS, sel.GetMinSuffixComponents())
+	}
+	if sel.HasMaxSuffixComponents {
+		n += writeNonNegativeIntegerTlv(b[n:], MAX_SUFFIX_COMPONENTS, sel.GetMaxSuffixComponents())
+	}
+	if sel.HasPublisherPublicKeyLocator {
+		pubKey := sel.GetPublisherPublicKeyLocator()
+		n += writeHeader(b[n:], PUBLISHER_PUB_KEY_LOCATOR, keyLocatorSize(pubKey))
+		n += writeKeyLocator(b[n:], pubKey)
+	}
+	if sel.HasExclude {
+		n += writeExclude(b[n:], sel.GetExclude())
+	}
+	if sel.HasChildSelector {
+		n += writeNonNegativeIntegerTlv(b[n:], CHILD_SELECTOR, sel.GetChildSelector())
+	}
+	if sel.GetMustBeFresh() {
+		n += writeHeader(b[n:], MUST_BE_FRESH, 0)
 	}
-	return append(t, x), nil
+	return n
 }
 
-func encodeDataSignature(packet interface{}, t []Tlv) ([]Tlv, error) {
-	sig := packet.(packets.Data).GetSignature()
-	sigInfo := sig.GetsigInfo()
-	sigVal := sig.GetsigVal()
-	sigInfoTlv := encodeSignatureInfo(sigInfo)
-	sigValTlv := encodeSignatureValue(sigVal)
-	t = append(t, sigInfoTlv)
-	return append(t, sigValTlv), nil
+func excludeLength(ex name.Exclude) int {
+	l := 0
+	for _, comp := range ex {
+		l += componentSize(comp)
+	}
+	return l
 }
 
-func encodeSignatureInfo(sigInfo packets.SignatureInfo) Tlv {
-	sigType := sigInfo.GetsigType()
-	keyLoc := sigInfo.GetKeyLocator()
-	tmp := make([]Tlv, 2)
-	tmp[0] = encodeSignatureType(sigType)
-	tmp[1] = encodeKeyLocator(keyLoc)
-	var b bytes.Buffer
-	TlvsToBytes(tmp, &b)
-	result := Tlv{
-		T: SIGNATURE_INFO,
-		L: uint64(b.Len()),
-		V: b.Next(b.Len()),
+func writeExclude(b []byte, ex name.Exclude) int {
+	n := writeHeader(b, EXCLUDE, excludeLength(ex))
+	for _, comp := range ex {
+		n += writeNameComponent(b[n:], comp)
 	}
-	return result
+	return n
 }
 
-func encodeSignatureType(sigType uint64) Tlv {
-	x := EncodeNonNegativeInteger(sigType)
-	return Tlv{
-		T: SIGNATURE_TYPE,
-		L: uint64(len(x)),
-		V: x,
+// MetaInfo ::= META-INFO-TYPE TLV-LENGTH ContentType? FreshnessPeriod? FinalBlockId?
+func metaInfoLength(mi packets.MetaInfo) int {
+	l := 0
+	if ct := mi.GetContentType(); ct != packets.Unknown {
+		l += nonNegativeIntegerTlvSize(CONTENT_TYPE, uint64(ct))
 	}
+	if fp := mi.GetFreshnessPeriod(); fp != -1 {
+		l += nonNegativeIntegerTlvSize(FRESHNESS_PERIOD, uint64(fp))
+	}
+	if id := mi.GetFinalBlockID(); len(id.GetValue()) > 0 {
+		l += tlvSize(FINAL_BLOCK_ID, componentSize(id))
+	}
+	return l
 }
 
-func encodeKeyLocator(keyLoc packets.KeyLocator) Tlv {
-	var b bytes.Buffer
-	if keyLoc.HasName {
-		x := encodeName(keyLoc.Name)
-		err := TlvToBytes(x, &b)
-		if err != nil {
-			return Tlv{}
-		}
-		return Tlv{
-			T: KEY_LOCATOR,
-			L: uint64(b.Len()),
-			V: b.Next(b.Len()),
-		}
-	} else {
-		if keyLoc.HasKeyDigest {
-			x := encodeKeyDigest(keyLoc.KeyDigest)
-			err := TlvToBytes(x, &b)
-			if err != nil {
-				return Tlv{}
-			}
-			return Tlv{
-				T: KEY_LOCATOR,
-				L: uint64(b.Len()),
-				V: b.Next(b.Len()),
-			}
-		}
+func writeMetaInfo(b []byte, mi packets.MetaInfo) int {
+	n := writeHeader(b, META_INFO, metaInfoLength(mi))
+	if ct := mi.GetContentType(); ct != packets.Unknown {
+		n += writeNonNegativeIntegerTlv(b[n:], CONTENT_TYPE, uint64(ct))
+	}
+	if fp := mi.GetFreshnessPeriod(); fp != -1 {
+		n += writeNonNegativeIntegerTlv(b[n:], FRESHNESS_PERIOD, uint64(fp))
+	}
+	//the final block id holds a name component tlv
+	if id := mi.GetFinalBlockID(); len(id.GetValue()) > 0 {
+		n += writeHeader(b[n:], FINAL_BLOCK_ID, componentSize(id))
+		n += writeNameComponent(b[n:], id)
 	}
-	return Tlv{}
+	return n
 }
 
-func encodeKeyDigest(kd []byte) Tlv {
-	return Tlv{
-		T: KEY_DIGEST,
-		L: uint64(len(kd)),
-		V: kd,
+// SignatureInfo ::= SIGNATURE-INFO-TYPE TLV-LENGTH SignatureType KeyLocator?
+func signatureInfoLength(sigInfo packets.SignatureInfo) int {
+	return nonNegativeIntegerTlvSize(SIGNATURE_TYPE, sigInfo.GetsigType()) +
+		keyLocatorSize(sigInfo.GetKeyLocator())
+}
+
+func writeSignatureInfo(b []byte, sigInfo packets.SignatureInfo) int {
+	n := writeHeader(b, SIGNATURE_INFO, signatureInfoLength(sigInfo))
+	n += writeNonNegativeIntegerTlv(b[n:], SIGNATURE_TYPE, sigInfo.GetsigType())
+	n += writeKeyLocator(b[n:], sigInfo.GetKeyLocator())
+	return n
+}
+
+// KeyLocator ::= KEY-LOCATOR-TYPE TLV-LENGTH (Name | KeyDigest)
+//size of the whole key locator tlv, 0 if it holds neither a name nor a digest
+//in which case it is left out
+func keyLocatorSize(keyLoc packets.KeyLocator) int {
+	if keyLoc.HasName {
+		return tlvSize(KEY_LOCATOR, tlvSize(NAME, nameLength(keyLoc.Name)))
+	}
+	if keyLoc.HasKeyDigest {
+		return tlvSize(KEY_LOCATOR, tlvSize(KEY_DIGEST, len(keyLoc.KeyDigest)))
 	}
+	return 0
 }
 
-func encodeSignatureValue(sigVal []byte) Tlv {
-	return Tlv{
-		T: SIGNATURE_VALUE,
-		L: uint64(len(sigVal)),
-		V: sigVal,
+func writeKeyLocator(b []byte, keyLoc packets.KeyLocator) int {
+	if keyLoc.HasName {
+		n := writeHeader(b, KEY_LOCATOR, tlvSize(NAME, nameLength(keyLoc.Name)))
+		return n + writeName(b[n:], keyLoc.Name)
+	}
+	if keyLoc.HasKeyDigest {
+		n := writeHeader(b, KEY_LOCATOR, tlvSize(KEY_DIGEST, len(keyLoc.KeyDigest)))
+		return n + writeTlv(b[n:], KEY_DIGEST, keyLoc.KeyDigest)
 	}
+	return 0
 }
@@ -0,0 +1,264 @@
+package tlv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//sentinel errors returned (wrapped in a TlvError) by the tlv readers,
+//callers can test for them with errors.Is
+var (
+	//the buffer ends before the type or the length is complete
+	ErrTruncated = errors.New("tlv: truncated input")
+	//the length announces more bytes than the buffer holds
+	ErrLengthOverflow = errors.New("tlv: length exceeds remaining bytes")
+	//the var-number is not a legal TLV-TYPE or TLV-LENGTH
+	ErrBadVarNumber = errors.New("tlv: bad var-number")
+	//the packet is bigger than the limit set by the reader or the decoder
+	ErrPacketTooLarge = errors.New("tlv: packet exceeds maximum size")
+	//a tlv the decoder does not know is critical, the packet must be dropped
+	ErrCriticalType = errors.New("tlv: unrecognized critical type")
+	//the tlvs are nested deeper than DecodeOptions.MaxDepth
+	ErrTooDeep = errors.New("tlv: nesting too deep")
+	//a name or an exclude holds more components than DecodeOptions.MaxComponents
+	ErrTooManyComponents = errors.New("tlv: too many name components")
+	//the value of a field is longer than DecodeOptions.MaxFieldSize
+	ErrFieldTooLarge = errors.New("tlv: field exceeds maximum size")
+	//the value of a field has a length or content its kind does not allow
+	ErrBadValue = errors.New("tlv: illegal value")
+	//a field comes before a field it must follow
+	ErrFieldOrder = errors.New("tlv: field out of order")
+	//a field that can appear once appears twice
+	ErrDuplicateField = errors.New("tlv: duplicate field")
+	//a required field is not there
+	ErrMissingField = errors.New("tlv: missing required field")
+	//bytes are left after a tlv that must fill the buffer or the value holding it
+	ErrTrailingBytes = errors.New("tlv: trailing bytes after the tlv")
+	//an interest holds both Selectors (format v0.2) and a field only the format v0.3 has
+	ErrInterestFormat = errors.New("tlv: interest mixes v0.2 and v0.3 fields")
+	//the outer tlv is neither an interest nor a data, whether its type is critical or not
+	ErrUnknownPacketType = errors.New("tlv: unknown packet type")
+	//the ParametersSha256DigestComponent of an interest name does not match its parameters,
+	//or the interest has no parameters
+	ErrParametersDigest = errors.New("tlv: parameters digest does not match the application parameters")
+)
+
+//TlvError describes where framing failed: the type of the tlv being read
+//(0 if the type itself could not be read) and the offset of that tlv in the
+//buffer handed to the reader
+type TlvError struct {
+	Type   uint64
+	Offset int
+	Err    error
+}
+
+func (e *TlvError) Error() string {
+	return fmt.Sprintf("%v (type %s at offset %d)", e.Err, TypeName(e.Type), e.Offset)
+}
+
+func (e *TlvError) Unwrap() error {
+	return e.Err
+}
+
+//shifts the offset of a framing error by the position of the sub-buffer
+//it was read from, other errors are returned as they are
+func errorAt(err error, offset int) error {
+	if err == nil {
+		//errors.As makes e escape, nothing must be allocated on the success path
+		return nil
+	}
+	var e *TlvError
+	if errors.As(err, &e) {
+		return &TlvError{Type: e.Type, Offset: e.Offset + offset, Err: e.Err}
+	}
+	return err
+}
+
+//the error for an outer tlv that is neither an interest nor a data
+func unknownPacketError(t uint64) error {
+	return &TlvError{Type: t, Offset: 0, Err: ErrUnknownPacketType}
+}
+
+//UnknownTypeError reports the unrecognized critical type that caused a packet
+//to be rejected and the type of the tlv it was found in
+type UnknownTypeError struct {
+	Type   uint64
+	Parent uint64
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("%v %s in %s", ErrCriticalType, TypeName(e.Type), TypeName(e.Parent))
+}
+
+func (e *UnknownTypeError) Unwrap() error {
+	return ErrCriticalType
+}
+
+//ValueError reports a field whose value is not legal for its kind,
+//such as a 3 byte NonNegativeInteger or a MustBeFresh with a value
+type ValueError struct {
+	Type   uint64
+	Length int
+	Kind   string //what the value should have been
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("%v for %s: %d bytes is not %s", ErrBadValue, TypeName(e.Type), e.Length, e.Kind)
+}
+
+func (e *ValueError) Unwrap() error {
+	return ErrBadValue
+}
+
+//DecodeError says where decoding failed: the types of the tlvs from the packet down to
+//the one holding the problem (e.g. DATA > SIGNATURE_INFO > KEY_LOCATOR), the offset of the
+//offending tlv in the decoded buffer, its type and length, and the underlying error
+//(a *TlvError, *UnknownTypeError, *ValueError ...)
+type DecodeError struct {
+	Path   []uint64
+	Offset int //-1 if it is not known
+	Type   uint64
+	Length int
+	Err    error
+
+	//where the offending tlv is, until the offset is known: it starts at rel bytes
+	//into v, or if rel is -1 it is the tlv whose value is v
+	v   []byte
+	rel int
+	//value of the outermost tlv of the path, so the same tlv is not added twice
+	outer []byte
+}
+
+func (e *DecodeError) Error() string {
+	names := make([]string, len(e.Path))
+	for i, t := range e.Path {
+		names[i] = TypeName(t)
+	}
+	err := e.Err
+	var te *TlvError
+	if errors.As(err, &te) {
+		//the offset of a framing error is relative, the one of the DecodeError replaces it
+		err = te.Err
+	}
+	return fmt.Sprintf("%s: %v (type %s, length %d, offset %d)",
+		strings.Join(names, " > "), err, TypeName(e.Type), e.Length, e.Offset)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+//renders the bytes of b around the offending tlv, see HexExcerpt
+func (e *DecodeError) Excerpt(b []byte) string {
+	return HexExcerpt(b, e.Offset, 16)
+}
+
+//adds t to the path of a decode error, t being the tlv the decoder was working on.
+//the first call for an error also records where the offending tlv is: inside the value
+//of t for a framing error, t itself otherwise
+func fieldError(err error, t Tlv) error {
+	if err == nil {
+		return nil
+	}
+	var d *DecodeError
+	if errors.As(err, &d) {
+		if d.Path[0] == t.T && sameSlice(d.outer, t.V) {
+			return d
+		}
+		d.Path = append([]uint64{t.T}, d.Path...)
+		d.outer = t.V
+		return d
+	}
+	d = &DecodeError{Path: []uint64{t.T}, Offset: -1, Type: t.T, Length: len(t.V), Err: err, v: t.V, rel: -1, outer: t.V}
+	var te *TlvError
+	if errors.As(err, &te) && !errors.Is(err, ErrTooDeep) && !errors.Is(err, ErrTooManyComponents) {
+		//the depth and component limits are about t itself, the other framing errors
+		//are about a tlv inside its value
+		d.Type, d.Length, d.rel = te.Type, 0, te.Offset
+	}
+	return d
+}
+
+func sameSlice(a, b []byte) bool {
+	return len(a) == len(b) && cap(a) == cap(b)
+}
+
+//turns the position recorded by fieldError into an offset in packet, the buffer
+//the tlvs were read from. other errors are returned as they are
+func locate(err error, packet []byte) error {
+	var d *DecodeError
+	if !errors.As(err, &d) || d.Offset >= 0 {
+		return err
+	}
+	//the values are sub-slices of packet, their capacity gives their position
+	start := cap(packet) - cap(d.v)
+	if d.v == nil || start < 0 || start > len(packet) {
+		return err
+	}
+	if d.rel >= 0 {
+		d.Offset = start + d.rel
+		if d.Offset > len(packet) {
+			d.Offset = -1
+			return err
+		}
+		if errors.Is(err, ErrMissingField) {
+			return err //the offset is where the field should be, there is no header to read
+		}
+		if t, l, _, _, err := readHeader(packet[d.Offset:], false); err == nil {
+			d.Type, d.Length = t, int(l)
+		}
+		return err
+	}
+	//the header is right before the value, its size depends on the encoding of the type and length
+	for _, ts := range []int{1, 3, 5, 9} {
+		for _, ls := range []int{1, 3, 5, 9} {
+			o := start - ts - ls
+			if o < 0 {
+				continue
+			}
+			t, l, hts, hls, herr := readHeader(packet[o:], false)
+			if herr == nil && hts == ts && hls == ls && t == d.Type && int(l) == len(d.v) {
+				d.Offset = o
+				return err
+			}
+		}
+	}
+	return err
+}
+
+//renders the bytes of b around offset as hex, context bytes on each side, the byte
+//at offset being in brackets:
+//
+//	000010  08 01 61 0a 04 [fd] 03 20 ...
+func HexExcerpt(b []byte, offset, context int) string {
+	if offset < 0 || offset > len(b) {
+		return "offset out of range"
+	}
+	from, to := offset-context, offset+context+1
+	if from < 0 {
+		from = 0
+	}
+	if to > len(b) {
+		to = len(b)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%06x ", from)
+	if from > 0 {
+		sb.WriteString(" ...")
+	}
+	for i := from; i < to; i++ {
+		if i == offset {
+			fmt.Fprintf(&sb, " [%02x]", b[i])
+		} else {
+			fmt.Fprintf(&sb, " %02x", b[i])
+		}
+	}
+	if offset == len(b) {
+		sb.WriteString(" [end]")
+	}
+	if to < len(b) {
+		sb.WriteString(" ...")
+	}
+	return sb.String()
+}
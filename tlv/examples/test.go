@@ -12,7 +12,7 @@ func main() {
 	//testing with interest
 
 	d := []byte{
-		0x05, 27,
+		0x05, 33,
 		// Name
 		0x07, 5,
 		0x08, 3, 'f', 'o', 'o',
@@ -59,7 +59,7 @@ func main() {
 		//   MustBeFresh?
 		0x12, 0,
 		// Nonce
-		// 0x0a, 4, 'a', 'b', 'c', 'd',
+		0x0a, 4, 'a', 'b', 'c', 'd',
 		// // InterestLifetime? (1000ms)
 		// 0x0c, 2, 0x03, 0xe8,
 	}
@@ -70,7 +70,11 @@ func main() {
 
 	start := time.Now()
 	for i := 0; i < 1000; i++ {
-		result := tlv.Decode(d)
+		result, err := tlv.Decode(d)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
 		// var b bytes.Buffer
 		y := result.(packets.Interest)
@@ -70,7 +70,7 @@
 
 // 	start := time.Now()
 // 	for i := 0; i < 1000; i++ {
-// 		result := tlv.ConcurrentDecode(d)
+// 		result, _ := tlv.ConcurrentDecode(d)
 
 // 		// var b bytes.Buffer
 // 		y := result.(packets.Interest)
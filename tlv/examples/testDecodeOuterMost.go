@@ -69,10 +69,11 @@
 // 	// }
 
 // 	chResult := make(chan packets.NdnPacket, 1000)
+// 	chErr := make(chan error, 1000)
 
 // 	start := time.Now()
 // 	for i := 0; i < 1000; i++ {
-// 		go tlv.DecodeOuterMostConcurrency(d, chResult)
+// 		go tlv.DecodeOuterMostConcurrency(d, chResult, chErr)
 
 // 		// var b bytes.Buffer
 // 		// y := <-chResult.(packets.Interest)
@@ -0,0 +1,75 @@
+// package main
+
+// allocations per packet of the pooled codec compared to the plain one
+// (see the README for the results)
+
+// import (
+// 	"bytes"
+// 	"fmt"
+// 	"ndn-router/nfd/tlv"
+// 	"ndn-router/nfd/tlv/packets"
+// 	"runtime"
+// 	"time"
+// )
+
+// //allocations and time per packet for f
+// func measure(label string, n int, f func()) {
+// 	var before, after runtime.MemStats
+// 	runtime.GC()
+// 	runtime.ReadMemStats(&before)
+// 	start := time.Now()
+// 	for i := 0; i < n; i++ {
+// 		f()
+// 	}
+// 	elapsed := time.Since(start)
+// 	runtime.ReadMemStats(&after)
+// 	fmt.Printf("%-28s %6.1f allocs/packet %7.0f bytes/packet %8.0f ns/packet\n", label,
+// 		float64(after.Mallocs-before.Mallocs)/float64(n),
+// 		float64(after.TotalAlloc-before.TotalAlloc)/float64(n),
+// 		float64(elapsed.Nanoseconds())/float64(n))
+// }
+
+// func main() {
+// 	d := []byte{
+// 		0x05, 39,
+// 		// Name
+// 		0x07, 15,
+// 		0x08, 3, 'f', 'o', 'o',
+// 		0x08, 3, 'b', 'a', 'r',
+// 		0x08, 3, 'b', 'a', 'z',
+// 		// Selectors
+// 		0x09, 10,
+// 		0x0d, 1, 1,
+// 		0x10, 5, 0x08, 1, 'a', 0x13, 0,
+// 		// Nonce
+// 		0x0a, 4, 'a', 'b', 'c', 'd',
+// 		// InterestLifetime (1000ms)
+// 		0x0c, 2, 0x03, 0xe8,
+// 	}
+// 	const n = 100000
+// 	interest, _ := tlv.Decode(d)
+// 	var b bytes.Buffer
+
+// 	measure("Decode", n, func() {
+// 		tlv.Decode(d)
+// 	})
+// 	measure("DecodePooled", n, func() {
+// 		p, _ := tlv.DecodePooled(d, tlv.DecodeOptions{})
+// 		p.(*packets.Interest).Release()
+// 	})
+// 	measure("DecodePooled ZeroCopy", n, func() {
+// 		p, _ := tlv.DecodePooled(d, tlv.DecodeOptions{ZeroCopy: true})
+// 		p.(*packets.Interest).Release()
+// 	})
+// 	measure("EncodeToBytes", n, func() {
+// 		tlv.EncodeToBytes(interest)
+// 	})
+// 	measure("EncodePooled", n, func() {
+// 		buf, _ := tlv.EncodePooled(interest)
+// 		buf.Release()
+// 	})
+// 	measure("Encode (bytes.Buffer)", n, func() {
+// 		b.Reset()
+// 		tlv.Encode(interest, &b)
+// 	})
+// }
@@ -0,0 +1,170 @@
+package tlv
+
+import (
+	"errors"
+	"time"
+)
+
+//Handler receives the fields of a packet from DecodeWith, in wire order, without a
+//packets.Interest or packets.Data being built. the Tlv and []byte arguments point into
+//the decoded buffer.
+//a method returning StopDecoding ends the decoding without error, any other error
+//ends it and is given back by DecodeWith. embed NopHandler to only implement some of them
+type Handler interface {
+	//the type of the packet, INTEREST or DATA, before any field
+	OnPacket(t uint64) error
+	//the name of the packet, followed by one OnNameComponent per component
+	OnName(n Tlv) error
+	OnNameComponent(c Tlv) error
+	//each field of the selectors (format v0.2)
+	OnSelector(s Tlv) error
+	//the flags of an interest of the format v0.3, the MustBeFresh of the selectors being an OnSelector
+	OnCanBePrefix() error
+	OnMustBeFresh() error
+	//each name of the forwarding hint, its components are not read
+	OnForwardingHint(n Tlv) error
+	OnNonce(nonce [4]byte) error
+	//the lifetime as packets.Interest holds it, the duration being the number of milliseconds
+	OnLifetime(lifetime time.Duration) error
+	OnHopLimit(hopLimit uint8) error
+	OnApplicationParameters(params []byte) error
+	OnContent(content []byte) error
+	OnSignatureInfo(info Tlv) error
+}
+
+//returned by a Handler method to stop the decoding
+var StopDecoding = errors.New("tlv: stop decoding")
+
+//NopHandler ignores all the events
+type NopHandler struct{}
+
+func (NopHandler) OnPacket(t uint64) error                 { return nil }
+func (NopHandler) OnName(n Tlv) error                      { return nil }
+func (NopHandler) OnNameComponent(c Tlv) error             { return nil }
+func (NopHandler) OnSelector(s Tlv) error                  { return nil }
+func (NopHandler) OnCanBePrefix() error                    { return nil }
+func (NopHandler) OnMustBeFresh() error                    { return nil }
+func (NopHandler) OnForwardingHint(n Tlv) error            { return nil }
+func (NopHandler) OnNonce(nonce [4]byte) error             { return nil }
+func (NopHandler) OnLifetime(lifetime time.Duration) error { return nil }
+func (NopHandler) OnHopLimit(hopLimit uint8) error         { return nil }
+func (NopHandler) OnApplicationParameters(p []byte) error  { return nil }
+func (NopHandler) OnContent(content []byte) error          { return nil }
+func (NopHandler) OnSignatureInfo(info Tlv) error          { return nil }
+
+//DecodeWith reads the interest or data held by b and calls the methods of h for its fields,
+//a field coming after the one h stopped at is not read at all.
+//the fields are read one by one with the framing of ParseTlvsFromBytes, the name components, integer, flag,
+//nonce and hop limit values are checked like Decode does, unknown critical types are an
+//*UnknownTypeError and unknown non-critical ones are skipped. fields that have no event (MetaInfo,
+//SignatureValue ...) are skipped too. the order and presence of the fields is not checked,
+//use Validate for that. like Decode, the packet can not be bigger than MaxPacketSize
+func DecodeWith(b []byte, h Handler) error {
+	t, err, ts, ls := tlvFromBytes(b, MaxPacketSize, false)
+	if err != nil {
+		return err
+	}
+	var rules []fieldRule
+	switch t.T {
+	case INTEREST:
+		if err := checkInterestFormat(t); err != nil {
+			return errorAt(err, ts+ls)
+		}
+		rules = interestRulesOf(t)
+	case DATA:
+		rules = dataRules
+	default:
+		return unknownPacketError(t.T)
+	}
+	err = h.OnPacket(t.T)
+	if err == nil {
+		err = eachField(t, rules, func(f Tlv) error {
+			return fieldEvent(f, h)
+		})
+	}
+	if err == StopDecoding {
+		return nil
+	}
+	return errorAt(err, ts+ls)
+}
+
+//calls fn for each field of t known by the rules, nil rules accepting any field.
+//offsets of the errors are relative to the value of t
+func eachField(t Tlv, rules []fieldRule, fn func(f Tlv) error) error {
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		if rules == nil || hasRule(rules, f.T) {
+			if err := fn(f); err != nil {
+				return errorAt(err, i+ts+ls)
+			}
+		} else if IsCritical(f.T) {
+			return &UnknownTypeError{Type: f.T, Parent: t.T}
+		}
+		i += ts + ls + int(f.L)
+	}
+	return nil
+}
+
+func hasRule(rules []fieldRule, t uint64) bool {
+	return ruleIndex(rules, t) >= 0
+}
+
+func fieldEvent(f Tlv, h Handler) error {
+	switch f.T {
+	case NAME:
+		if err := h.OnName(f); err != nil {
+			return err
+		}
+		//like Decode, a component is checked before its event (Any, digest length ...)
+		return eachField(f, nil, func(c Tlv) error {
+			if err := checkNameComponent(c, NAME); err != nil {
+				return err
+			}
+			return h.OnNameComponent(c)
+		})
+	case SELECTORS:
+		return eachField(f, selectorRules, h.OnSelector)
+	case CAN_BE_PREFIX:
+		if err := checkFlag(f); err != nil {
+			return err
+		}
+		return h.OnCanBePrefix()
+	case MUST_BE_FRESH:
+		if err := checkFlag(f); err != nil {
+			return err
+		}
+		return h.OnMustBeFresh()
+	case FORWARDING_HINT:
+		return eachField(f, forwardingHintRules, h.OnForwardingHint)
+	case NONCE:
+		v, err := DecodeFixedValue(f, 4)
+		if err != nil {
+			return err
+		}
+		nonce := [4]byte{}
+		copy(nonce[:], v)
+		return h.OnNonce(nonce)
+	case INTEREST_LIFETIME:
+		x, err := DecodeNonNegativeIntegerValue(f)
+		if err != nil {
+			return err
+		}
+		return h.OnLifetime(time.Duration(x))
+	case HOP_LIMIT:
+		v, err := DecodeFixedValue(f, 1)
+		if err != nil {
+			return err
+		}
+		return h.OnHopLimit(v[0])
+	case APPLICATION_PARAMETERS:
+		return h.OnApplicationParameters(f.V)
+	case CONTENT:
+		return h.OnContent(f.V)
+	case SIGNATURE_INFO:
+		return h.OnSignatureInfo(f)
+	}
+	return nil
+}
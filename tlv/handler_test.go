@@ -0,0 +1,47 @@
+package tlv
+
+import "testing"
+
+//counts the components it is given
+type componentCounter struct {
+	NopHandler
+	n int
+}
+
+func (h *componentCounter) OnNameComponent(c Tlv) error {
+	h.n++
+	return nil
+}
+
+func TestDecodeWithChecksComponents(t *testing.T) {
+	for _, text := range []string{
+		`INTEREST { NAME { NAME_COMPONENT "a" ANY "b" } NONCE x"01020304" }`,
+		`INTEREST { NAME { NAME_COMPONENT "a" IMPLICIT_DIGEST x"01" } NONCE x"01020304" }`,
+	} {
+		b := parseCorpus(t, text)
+		h := &componentCounter{}
+		err := DecodeWith(b, h)
+		if err == nil {
+			t.Errorf("%s: no error", text)
+		}
+		if _, decodeErr := Decode(b); sentinel(err) != sentinel(decodeErr) {
+			t.Errorf("%s: DecodeWith gives %v, Decode %v", text, err, decodeErr)
+		}
+		if h.n != 1 {
+			t.Errorf("%s: %d components before the error, want 1", text, h.n)
+		}
+	}
+}
+
+func TestDecodeWithAllocs(t *testing.T) {
+	h := &componentCounter{}
+	for _, c := range corpus {
+		b := parseCorpus(t, c.text)
+		if Validate(b) != nil {
+			continue
+		}
+		if allocs := testing.AllocsPerRun(100, func() { DecodeWith(b, h) }); allocs != 0 {
+			t.Errorf("%s: DecodeWith: %v allocs, want 0", c.name, allocs)
+		}
+	}
+}
@@ -3,11 +3,13 @@ package tlv
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"strconv"
+	"unicode/utf8"
 )
 
+//reads a NonNegativeInteger, 0 for a value of an illegal length. the Decode...Value
+//functions below check the value of a field and give back a *ValueError instead
 func DecodeNonNegativeInteger(b []byte) uint64 {
 	switch len(b) {
 	case 1:
@@ -23,74 +25,147 @@ func DecodeNonNegativeInteger(b []byte) uint64 {
 	}
 }
 
+//reads a NonNegativeInteger, which must be 1, 2, 4 or 8 bytes long
+func DecodeNonNegativeIntegerValue(t Tlv) (uint64, error) {
+	switch len(t.V) {
+	case 1, 2, 4, 8:
+		return DecodeNonNegativeInteger(t.V), nil
+	}
+	return 0, &ValueError{Type: t.T, Length: len(t.V), Kind: "a NonNegativeInteger"}
+}
+
+//gives back the value of t, which must be exactly size bytes long (a nonce ...)
+func DecodeFixedValue(t Tlv, size int) ([]byte, error) {
+	if len(t.V) != size {
+		return nil, &ValueError{Type: t.T, Length: len(t.V), Kind: "a " + strconv.Itoa(size) + " byte value"}
+	}
+	return t.V, nil
+}
+
+//reads a flag such as MustBeFresh: the presence of the tlv is the value, it must be empty
+func DecodeFlagValue(t Tlv) (bool, error) {
+	if len(t.V) != 0 {
+		return false, &ValueError{Type: t.T, Length: len(t.V), Kind: "an empty flag"}
+	}
+	return true, nil
+}
+
+//reads a value holding UTF-8 text
+func DecodeStringValue(t Tlv) (string, error) {
+	if !utf8.Valid(t.V) {
+		return "", &ValueError{Type: t.T, Length: len(t.V), Kind: "a UTF-8 string"}
+	}
+	return string(t.V), nil
+}
+
 func EncodeNonNegativeInteger(n uint64) []byte {
-	b := [8]byte{}
+	b := make([]byte, nonNegativeIntegerSize(n))
+	putNonNegativeInteger(b, n)
+	return b
+}
+
+//number of bytes used by the shortest NonNegativeInteger encoding of n (1, 2, 4 or 8)
+func nonNegativeIntegerSize(n uint64) int {
 	if n <= 0xFF {
-		b[0] = uint8(n)
-		return b[:1]
+		return 1
 	} else if n <= 0xFFFF {
-		binary.BigEndian.PutUint16(b[:], uint16(n))
-		return b[:2]
+		return 2
 	} else if n <= 0xFFFFFFFF {
-		binary.BigEndian.PutUint32(b[:], uint32(n))
-		return b[:4]
-	} else {
-		binary.BigEndian.PutUint64(b[:], uint64(n))
-		return b[:8]
+		return 4
 	}
+	return 8
 }
 
-//variable length decoding
-func varDecoding(packet []byte) (val uint64, size int) {
-	concat := "" //used to concatenate the bytes that will form the type's value
-	switch packet[0] {
+//writes n to b using the shortest NonNegativeInteger encoding,
+//b must have room for nonNegativeIntegerSize(n) bytes, the number of bytes written is returned
+func putNonNegativeInteger(b []byte, n uint64) int {
+	switch nonNegativeIntegerSize(n) {
+	case 1:
+		b[0] = uint8(n)
+		return 1
+	case 2:
+		binary.BigEndian.PutUint16(b, uint16(n))
+		return 2
+	case 4:
+		binary.BigEndian.PutUint32(b, uint32(n))
+		return 4
+	default:
+		binary.BigEndian.PutUint64(b, n)
+		return 8
+	}
+}
+
+//reads a VAR-NUMBER (1, 3, 5 or 9 bytes) from the start of b and returns its value
+//and the number of bytes it used, without allocating.
+//returns ErrTruncated if b is too short for the announced size, and if canonical
+//is set ErrBadVarNumber for a value that would fit in a shorter encoding (e.g. 0xFD 0x00 0x05)
+func DecodeVarNumber(b []byte, canonical bool) (val uint64, size int, err error) {
+	if len(b) == 0 {
+		return 0, 0, ErrTruncated
+	}
+	var min uint64 //smallest value allowed for this size
+	switch b[0] {
 	case 0xFD: // 2 bytes
-		size = 3
+		size, min = 3, 0xFD
 	case 0xFE: // 4 bytes
-		size = 5
-
+		size, min = 5, 0x10000
 	case 0xFF: // 8 bytes
-		size = 9
-
+		size, min = 9, 0x100000000
 	default: //1 byte
-		size = 1
-		concat += fmt.Sprintf("%02x", packet[0])
+		return uint64(b[0]), 1, nil
 	}
-	if size != 1 {
-		for i := 1; i < size; i++ {
-			concat += fmt.Sprintf("%02x", packet[i])
-		}
+	if len(b) < size {
+		return 0, 0, ErrTruncated
 	}
-	val, _ = strconv.ParseUint(concat, 16, 64) // conversion to decimal
-	return
+	switch size {
+	case 3:
+		val = uint64(binary.BigEndian.Uint16(b[1:3]))
+	case 5:
+		val = uint64(binary.BigEndian.Uint32(b[1:5]))
+	default:
+		val = binary.BigEndian.Uint64(b[1:9])
+	}
+	if canonical && val < min {
+		return 0, 0, ErrBadVarNumber
+	}
+	return val, size, nil
 }
 
-// takes the type, or length as int and transforms it to []byte
-// considering the variable length encoding
-func varEncoding(num uint64, w io.Writer) error {
-
-	if num < 253 {
-		return binary.Write(w, binary.BigEndian, uint8(num))
+//number of bytes needed to encode num as a VAR-NUMBER
+func varSize(num uint64) int {
+	switch {
+	case num < 0xFD:
+		return 1
+	case num <= 0xFFFF:
+		return 3
+	case num <= 0xFFFFFFFF:
+		return 5
+	default:
+		return 9
 	}
+}
 
-	var first byte
-	var val interface{}
-	if num <= 0xFFFF {
-		first = 253
-		val = uint16(num)
-	} else if num <= 0xFFFFFFFF {
-		first = 254
-		val = uint32(num)
-	} else {
-		first = 255
-		val = num
-	}
-	err := binary.Write(w, binary.BigEndian, first)
-	if err != nil {
-		return err
+// takes the type, or length as int and writes it to b
+// considering the variable length encoding, always using the shortest form.
+// b must have room for varSize(num) bytes, the number of bytes written is returned
+func varEncoding(num uint64, b []byte) int {
+	switch {
+	case num < 0xFD:
+		b[0] = uint8(num)
+		return 1
+	case num <= 0xFFFF:
+		b[0] = 0xFD
+		binary.BigEndian.PutUint16(b[1:3], uint16(num))
+		return 3
+	case num <= 0xFFFFFFFF:
+		b[0] = 0xFE
+		binary.BigEndian.PutUint32(b[1:5], uint32(num))
+		return 5
+	default:
+		b[0] = 0xFF
+		binary.BigEndian.PutUint64(b[1:9], num)
+		return 9
 	}
-
-	return binary.Write(w, binary.BigEndian, val)
 }
 
 //returns bytes from a buffer
@@ -0,0 +1,5 @@
+package tlv
+
+//the NDNLPv2 link protocol packet is not written by hand, its types and codec
+//are generated from lp.tlvspec by tlvGenerator.go
+//go:generate go run tlvGenerator.go -in lp.tlvspec -out lp_gen.go -unknown ndnlp
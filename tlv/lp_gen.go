@@ -0,0 +1,487 @@
+// Code generated by tlvGenerator.go from lp.tlvspec; DO NOT EDIT.
+
+package tlv
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	LP_PACKET         = 0x64
+	FRAGMENT          = 0x50
+	SEQUENCE          = 0x51
+	FRAG_INDEX        = 0x52
+	FRAG_COUNT        = 0x53
+	PIT_TOKEN         = 0x62
+	NACK              = 0x320
+	NACK_REASON       = 0x321
+	INCOMING_FACE_ID  = 0x32c
+	NEXT_HOP_FACE_ID  = 0x330
+	CACHE_POLICY      = 0x334
+	CACHE_POLICY_TYPE = 0x335
+	CONGESTION_MARK   = 0x340
+	ACK               = 0x344
+	TX_SEQUENCE       = 0x348
+	NON_DISCOVERY     = 0x34c
+)
+
+func init() {
+	mustRegisterType(TypeInfo{Type: LP_PACKET, Name: "LP_PACKET", Kind: KindNested})
+	mustRegisterType(TypeInfo{Type: FRAGMENT, Name: "FRAGMENT", Kind: KindBytes})
+	mustRegisterType(TypeInfo{Type: SEQUENCE, Name: "SEQUENCE", Kind: KindBytes, Decode: fixedIntegerDecoder(8)})
+	mustRegisterType(TypeInfo{Type: FRAG_INDEX, Name: "FRAG_INDEX", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: FRAG_COUNT, Name: "FRAG_COUNT", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: PIT_TOKEN, Name: "PIT_TOKEN", Kind: KindBytes})
+	mustRegisterType(TypeInfo{Type: NACK, Name: "NACK", Kind: KindNested})
+	mustRegisterType(TypeInfo{Type: NACK_REASON, Name: "NACK_REASON", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: INCOMING_FACE_ID, Name: "INCOMING_FACE_ID", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: NEXT_HOP_FACE_ID, Name: "NEXT_HOP_FACE_ID", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: CACHE_POLICY, Name: "CACHE_POLICY", Kind: KindNested})
+	mustRegisterType(TypeInfo{Type: CACHE_POLICY_TYPE, Name: "CACHE_POLICY_TYPE", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: CONGESTION_MARK, Name: "CONGESTION_MARK", Kind: KindNonNegativeInteger})
+	mustRegisterType(TypeInfo{Type: ACK, Name: "ACK", Kind: KindBytes, Decode: fixedIntegerDecoder(8)})
+	mustRegisterType(TypeInfo{Type: TX_SEQUENCE, Name: "TX_SEQUENCE", Kind: KindBytes, Decode: fixedIntegerDecoder(8)})
+	mustRegisterType(TypeInfo{Type: NON_DISCOVERY, Name: "NON_DISCOVERY", Kind: KindFlag})
+}
+
+// LpPacket ::= LP_PACKET TLV-LENGTH Sequence? FragIndex? FragCount? PitToken? Nack? IncomingFaceId? NextHopFaceId? CachePolicy? CongestionMark? Ack* TxSequence? NonDiscovery? Fragment?
+type LpPacket struct {
+	HasSequence       bool
+	Sequence          uint64
+	HasFragIndex      bool
+	FragIndex         uint64
+	HasFragCount      bool
+	FragCount         uint64
+	HasPitToken       bool
+	PitToken          []byte
+	HasNack           bool
+	Nack              Nack
+	HasIncomingFaceId bool
+	IncomingFaceId    uint64
+	HasNextHopFaceId  bool
+	NextHopFaceId     uint64
+	HasCachePolicy    bool
+	CachePolicy       CachePolicy
+	HasCongestionMark bool
+	CongestionMark    uint64
+	Ack               []uint64
+	HasTxSequence     bool
+	TxSequence        uint64
+	NonDiscovery      bool
+	HasFragment       bool
+	Fragment          []byte
+}
+
+func lpPacketLength(p *LpPacket) int {
+	l := 0
+	if p.HasSequence {
+		l += tlvSize(SEQUENCE, 8)
+	}
+	if p.HasFragIndex {
+		l += nonNegativeIntegerTlvSize(FRAG_INDEX, p.FragIndex)
+	}
+	if p.HasFragCount {
+		l += nonNegativeIntegerTlvSize(FRAG_COUNT, p.FragCount)
+	}
+	if p.HasPitToken {
+		l += tlvSize(PIT_TOKEN, len(p.PitToken))
+	}
+	if p.HasNack {
+		l += tlvSize(NACK, nackLength(&p.Nack))
+	}
+	if p.HasIncomingFaceId {
+		l += nonNegativeIntegerTlvSize(INCOMING_FACE_ID, p.IncomingFaceId)
+	}
+	if p.HasNextHopFaceId {
+		l += nonNegativeIntegerTlvSize(NEXT_HOP_FACE_ID, p.NextHopFaceId)
+	}
+	if p.HasCachePolicy {
+		l += tlvSize(CACHE_POLICY, cachePolicyLength(&p.CachePolicy))
+	}
+	if p.HasCongestionMark {
+		l += nonNegativeIntegerTlvSize(CONGESTION_MARK, p.CongestionMark)
+	}
+	l += len(p.Ack) * tlvSize(ACK, 8)
+	if p.HasTxSequence {
+		l += tlvSize(TX_SEQUENCE, 8)
+	}
+	if p.NonDiscovery {
+		l += tlvSize(NON_DISCOVERY, 0)
+	}
+	if p.HasFragment {
+		l += tlvSize(FRAGMENT, len(p.Fragment))
+	}
+	return l
+}
+
+func writeLpPacket(b []byte, p *LpPacket) int {
+	n := writeHeader(b, LP_PACKET, lpPacketLength(p))
+	if p.HasSequence {
+		n += writeHeader(b[n:], SEQUENCE, 8)
+		binary.BigEndian.PutUint64(b[n:], p.Sequence)
+		n += 8
+	}
+	if p.HasFragIndex {
+		n += writeNonNegativeIntegerTlv(b[n:], FRAG_INDEX, p.FragIndex)
+	}
+	if p.HasFragCount {
+		n += writeNonNegativeIntegerTlv(b[n:], FRAG_COUNT, p.FragCount)
+	}
+	if p.HasPitToken {
+		n += writeTlv(b[n:], PIT_TOKEN, p.PitToken)
+	}
+	if p.HasNack {
+		n += writeNack(b[n:], &p.Nack)
+	}
+	if p.HasIncomingFaceId {
+		n += writeNonNegativeIntegerTlv(b[n:], INCOMING_FACE_ID, p.IncomingFaceId)
+	}
+	if p.HasNextHopFaceId {
+		n += writeNonNegativeIntegerTlv(b[n:], NEXT_HOP_FACE_ID, p.NextHopFaceId)
+	}
+	if p.HasCachePolicy {
+		n += writeCachePolicy(b[n:], &p.CachePolicy)
+	}
+	if p.HasCongestionMark {
+		n += writeNonNegativeIntegerTlv(b[n:], CONGESTION_MARK, p.CongestionMark)
+	}
+	for i := range p.Ack {
+		n += writeHeader(b[n:], ACK, 8)
+		binary.BigEndian.PutUint64(b[n:], p.Ack[i])
+		n += 8
+	}
+	if p.HasTxSequence {
+		n += writeHeader(b[n:], TX_SEQUENCE, 8)
+		binary.BigEndian.PutUint64(b[n:], p.TxSequence)
+		n += 8
+	}
+	if p.NonDiscovery {
+		n += writeHeader(b[n:], NON_DISCOVERY, 0)
+	}
+	if p.HasFragment {
+		n += writeTlv(b[n:], FRAGMENT, p.Fragment)
+	}
+	return n
+}
+
+// decodes t into p, reusing the slices p already holds
+func decodeLpPacketInto(t Tlv, p *LpPacket) error {
+	ack := p.Ack[:0]
+	*p = LpPacket{}
+	p.Ack = ack
+	last := -1 //index of the last field read, they must come in order
+	var f Tlv
+	var ts, ls int
+	for i := 0; i < len(t.V); i += ts + ls + int(f.L) {
+		var err error
+		f, err, ts, ls = TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		switch f.T {
+		case SEQUENCE:
+			if last > 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 0
+			p.HasSequence = true
+			v, err := DecodeFixedValue(f, 8)
+			if err != nil {
+				return err
+			}
+			p.Sequence = binary.BigEndian.Uint64(v)
+		case FRAG_INDEX:
+			if last > 1 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 1 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 1
+			p.HasFragIndex = true
+			if p.FragIndex, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		case FRAG_COUNT:
+			if last > 2 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 2 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 2
+			p.HasFragCount = true
+			if p.FragCount, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		case PIT_TOKEN:
+			if last > 3 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 3 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 3
+			p.HasPitToken = true
+			p.PitToken = f.V
+		case NACK:
+			if last > 4 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 4 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 4
+			p.HasNack = true
+			if err := decodeNackInto(f, &p.Nack); err != nil {
+				return errorAt(err, i+ts+ls)
+			}
+		case INCOMING_FACE_ID:
+			if last > 5 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 5 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 5
+			p.HasIncomingFaceId = true
+			if p.IncomingFaceId, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		case NEXT_HOP_FACE_ID:
+			if last > 6 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 6 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 6
+			p.HasNextHopFaceId = true
+			if p.NextHopFaceId, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		case CACHE_POLICY:
+			if last > 7 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 7 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 7
+			p.HasCachePolicy = true
+			if err := decodeCachePolicyInto(f, &p.CachePolicy); err != nil {
+				return errorAt(err, i+ts+ls)
+			}
+		case CONGESTION_MARK:
+			if last > 8 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 8 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 8
+			p.HasCongestionMark = true
+			if p.CongestionMark, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		case ACK:
+			if last > 9 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			last = 9
+			p.Ack = append(p.Ack, 0)
+			v, err := DecodeFixedValue(f, 8)
+			if err != nil {
+				return err
+			}
+			p.Ack[len(p.Ack)-1] = binary.BigEndian.Uint64(v)
+		case TX_SEQUENCE:
+			if last > 10 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 10 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 10
+			p.HasTxSequence = true
+			v, err := DecodeFixedValue(f, 8)
+			if err != nil {
+				return err
+			}
+			p.TxSequence = binary.BigEndian.Uint64(v)
+		case NON_DISCOVERY:
+			if last > 11 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 11 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 11
+			if p.NonDiscovery, err = DecodeFlagValue(f); err != nil {
+				return err
+			}
+		case FRAGMENT:
+			if last > 12 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 12 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 12
+			p.HasFragment = true
+			p.Fragment = f.V
+		default:
+			// NDNLPv2: an unknown header field can only be ignored if its type is in [800, 959]
+			// and its two least significant bits are 0
+			if f.T < 800 || f.T > 959 || f.T&0x03 != 0 {
+				return &UnknownTypeError{Type: f.T, Parent: t.T}
+			}
+		}
+	}
+	return nil
+}
+
+// number of bytes the LpPacket takes on the wire
+func (p *LpPacket) EncodedLength() int {
+	return tlvSize(LP_PACKET, lpPacketLength(p))
+}
+
+// writes the LpPacket at the start of b, which must hold at least EncodedLength() bytes,
+// and gives back the number of bytes written
+func (p *LpPacket) EncodeTo(b []byte) (int, error) {
+	if len(b) < p.EncodedLength() {
+		return 0, io.ErrShortBuffer
+	}
+	return writeLpPacket(b, p), nil
+}
+
+// decodes the single LpPacket held by b into p, reusing the slices p already holds.
+// byte fields point into b
+func DecodeLpPacket(b []byte, p *LpPacket) error {
+	t, err, ts, ls := TlvFromBytes(b)
+	if err != nil {
+		return err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return &TlvError{Type: t.T, Offset: ts + ls + int(t.L), Err: ErrTrailingBytes}
+	}
+	if t.T != LP_PACKET {
+		return unknownPacketError(t.T)
+	}
+	return errorAt(decodeLpPacketInto(t, p), ts+ls)
+}
+
+// Nack ::= NACK TLV-LENGTH NackReason?
+type Nack struct {
+	HasNackReason bool
+	NackReason    uint64
+}
+
+func nackLength(p *Nack) int {
+	l := 0
+	if p.HasNackReason {
+		l += nonNegativeIntegerTlvSize(NACK_REASON, p.NackReason)
+	}
+	return l
+}
+
+func writeNack(b []byte, p *Nack) int {
+	n := writeHeader(b, NACK, nackLength(p))
+	if p.HasNackReason {
+		n += writeNonNegativeIntegerTlv(b[n:], NACK_REASON, p.NackReason)
+	}
+	return n
+}
+
+// decodes t into p, reusing the slices p already holds
+func decodeNackInto(t Tlv, p *Nack) error {
+	*p = Nack{}
+	last := -1 //index of the last field read, they must come in order
+	var f Tlv
+	var ts, ls int
+	for i := 0; i < len(t.V); i += ts + ls + int(f.L) {
+		var err error
+		f, err, ts, ls = TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		switch f.T {
+		case NACK_REASON:
+			if last > 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 0
+			p.HasNackReason = true
+			if p.NackReason, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		default:
+			if IsCritical(f.T) {
+				return &UnknownTypeError{Type: f.T, Parent: t.T}
+			}
+		}
+	}
+	return nil
+}
+
+// CachePolicy ::= CACHE_POLICY TLV-LENGTH CachePolicyType
+type CachePolicy struct {
+	CachePolicyType uint64
+}
+
+func cachePolicyLength(p *CachePolicy) int {
+	l := 0
+	l += nonNegativeIntegerTlvSize(CACHE_POLICY_TYPE, p.CachePolicyType)
+	return l
+}
+
+func writeCachePolicy(b []byte, p *CachePolicy) int {
+	n := writeHeader(b, CACHE_POLICY, cachePolicyLength(p))
+	n += writeNonNegativeIntegerTlv(b[n:], CACHE_POLICY_TYPE, p.CachePolicyType)
+	return n
+}
+
+// decodes t into p, reusing the slices p already holds
+func decodeCachePolicyInto(t Tlv, p *CachePolicy) error {
+	*p = CachePolicy{}
+	var seen [1]bool
+	last := -1 //index of the last field read, they must come in order
+	var f Tlv
+	var ts, ls int
+	for i := 0; i < len(t.V); i += ts + ls + int(f.L) {
+		var err error
+		f, err, ts, ls = TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		switch f.T {
+		case CACHE_POLICY_TYPE:
+			if last > 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}
+			}
+			if last == 0 {
+				return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}
+			}
+			last = 0
+			seen[0] = true
+			if p.CachePolicyType, err = DecodeNonNegativeIntegerValue(f); err != nil {
+				return err
+			}
+		default:
+			if IsCritical(f.T) {
+				return &UnknownTypeError{Type: f.T, Parent: t.T}
+			}
+		}
+	}
+	if !seen[0] {
+		return &TlvError{Type: CACHE_POLICY_TYPE, Offset: len(t.V), Err: ErrMissingField}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package tlv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//lp_gen.go must be what the go:generate line of lp.go writes from lp.tlvspec
+func TestLpGenUpToDate(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go tool to run the generator")
+	}
+	src, err := os.ReadFile("lp.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []string
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "//go:generate go ") {
+			args = strings.Fields(strings.TrimPrefix(line, "//go:generate go "))
+		}
+	}
+	if args == nil {
+		t.Fatal("no go:generate line in lp.go")
+	}
+	out := filepath.Join(t.TempDir(), "lp_gen.go")
+	for i := range args {
+		if i > 0 && args[i-1] == "-out" {
+			args[i] = out
+		}
+	}
+	if msg, err := exec.Command(goTool, args...).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, msg)
+	}
+	want, err := os.ReadFile("lp_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("lp_gen.go is not up to date, run go generate")
+	}
+}
+
+func TestDecodeLpPacketUnknownFields(t *testing.T) {
+	for _, c := range []struct {
+		typ    uint64
+		ignore bool
+	}{
+		{0x324, true},  //804, in [800, 959] with the low bits 00
+		{0x3bc, true},  //956
+		{0x325, false}, //low bits 01
+		{0x326, false}, //low bits 10, not critical for IsCritical
+		{0x3c0, false}, //960
+		{0x66, false},  //not a header field, not critical for IsCritical
+	} {
+		b := NewNestedElement(LP_PACKET, NewElement(c.typ, []byte{1}), NewElement(FRAGMENT, []byte{2})).Encode()
+		var p LpPacket
+		err := DecodeLpPacket(b, &p)
+		if c.ignore && (err != nil || !bytes.Equal(p.Fragment, []byte{2})) {
+			t.Errorf("0x%x: %v, %+v", c.typ, err, p)
+		}
+		if !c.ignore && !errors.Is(err, ErrCriticalType) {
+			t.Errorf("0x%x: %v, want ErrCriticalType", c.typ, err)
+		}
+	}
+}
+
+func TestDecodeLpPacketErrors(t *testing.T) {
+	seq := NewElement(SEQUENCE, make([]byte, 8))
+	frag := NewElement(FRAGMENT, []byte{2})
+	for _, c := range []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"duplicate", NewNestedElement(LP_PACKET, seq, seq).Encode(), ErrDuplicateField},
+		{"order", NewNestedElement(LP_PACKET, frag, seq).Encode(), ErrFieldOrder},
+		{"bad value", NewNestedElement(LP_PACKET, NewElement(SEQUENCE, []byte{1})).Encode(), ErrBadValue},
+		{"missing", NewNestedElement(LP_PACKET, NewNestedElement(CACHE_POLICY)).Encode(), ErrMissingField},
+		{"trailing", append(NewNestedElement(LP_PACKET, frag).Encode(), 0), ErrTrailingBytes},
+		{"not a packet", NewNestedElement(INTEREST, frag).Encode(), ErrUnknownPacketType},
+	} {
+		var p LpPacket
+		if err := DecodeLpPacket(c.b, &p); !errors.Is(err, c.want) {
+			t.Errorf("%s: %v, want %v", c.name, err, c.want)
+		}
+	}
+	//the offset of a framing error is the one of the field in the packet
+	var p LpPacket
+	err := DecodeLpPacket(NewNestedElement(LP_PACKET, frag, seq).Encode(), &p)
+	var te *TlvError
+	if !errors.As(err, &te) || te.Type != SEQUENCE || te.Offset != 5 {
+		t.Errorf("order: %v, want SEQUENCE at offset 5", err)
+	}
+}
+
+func TestLpPacketAllocs(t *testing.T) {
+	in := LpPacket{HasSequence: true, Sequence: 7, HasNack: true, Ack: []uint64{1, 2}, HasFragment: true, Fragment: []byte{1, 2, 3}}
+	b := make([]byte, in.EncodedLength())
+	var p LpPacket
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := in.EncodeTo(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := DecodeLpPacket(b, &p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("%v allocs, want 0", allocs)
+	}
+}
@@ -0,0 +1,446 @@
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ndn-router/nfd/tlv/name"
+)
+
+//Marshal and Unmarshal encode and decode structs described with `tlv` tags,
+//so application formats can be defined without writing an encoder and a decoder:
+//
+//	type Ping struct {
+//		_       struct{}  `tlv:"0x80"`             // type of the struct itself (optional)
+//		Name    name.Name `tlv:"0x07"`
+//		Nonce   []byte    `tlv:"0x0a,fixed=4"`
+//		Timeout uint64    `tlv:"0x0c,optional,nonneg"`
+//		Urgent  bool      `tlv:"0x82"`             // present when true, empty value
+//		Hops    []Hop     `tlv:"0x84"`             // repeated, one tlv per element
+//		Extra   []Tlv     `tlv:",unknown"`         // unrecognized non-critical tlvs
+//	}
+//
+//the first element of a tag is the tlv type (hex or decimal), the options are:
+//  - optional : a zero value is left out, and the field may be missing when decoding
+//  - nonneg   : the integer is a NonNegativeInteger (the default for integers)
+//  - fixed=N  : the value is exactly N bytes, integers are then big endian on N bytes
+//  - unknown  : the field ([]Tlv) collects the tlvs no other field knows about
+//
+//supported field types are integers, bool, string, []byte, [N]byte, name.Name,
+//name.Component, structs (nested tlvs), pointers to those (nil is left out) and
+//slices of those (repeated fields). fields are encoded in the order of the struct,
+//and decoded in any order. missing fields that are not optional, pointers, bools or
+//repeated are an error ([]byte and name.Name are not repeated, they are always encoded),
+//as are duplicates and unrecognized critical types
+
+//the type of a field as described by its tag
+type fieldInfo struct {
+	index    int
+	name     string
+	typ      uint64
+	optional bool
+	fixed    int
+	repeated bool
+}
+
+type structInfo struct {
+	outer   uint64 //type of the struct itself, 0 if it has none
+	fields  []fieldInfo
+	byType  map[uint64]int
+	unknown int //index of the field collecting unknown tlvs, -1 if there is none
+}
+
+var (
+	structInfoCache  sync.Map //reflect.Type => *structInfo
+	reflectTlvSlice  = reflect.TypeOf([]Tlv(nil))
+	reflectName      = reflect.TypeOf(name.Name(nil))
+	reflectComponent = reflect.TypeOf(name.Component{})
+)
+
+//gives back the wire encoding of the struct v (or pointer to struct).
+//if v declares its own type the result is a single tlv, otherwise it is the
+//list of tlvs of its fields
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Marshal : --- %T is not a struct ---", v)
+	}
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	children, err := marshalFields(rv, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.outer != 0 {
+		return NewNestedElement(info.outer, children...).Encode(), nil
+	}
+	size := 0
+	for _, c := range children {
+		size += c.Size()
+	}
+	b := make([]byte, size)
+	n := 0
+	for _, c := range children {
+		n += c.EncodeTo(b[n:])
+	}
+	return b, nil
+}
+
+//decodes b into the struct pointed to by v, b being what Marshal gives back for that struct.
+//[]byte fields get their own copy of the bytes
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal : --- %T is not a pointer to a struct ---", v)
+	}
+	info, err := getStructInfo(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if info.outer == 0 {
+		return unmarshalFields(b, rv.Elem(), info, 0)
+	}
+	t, err, ts, ls := TlvFromBytes(b)
+	if err != nil {
+		return err
+	}
+	if t.T != info.outer {
+		return fmt.Errorf("Unmarshal : --- unexpected type 0x%x, want 0x%x ---", t.T, info.outer)
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return fmt.Errorf("Unmarshal : --- trailing bytes after the tlv ---")
+	}
+	return unmarshalFields(t.V, rv.Elem(), info, t.T)
+}
+
+//reads the tags of a struct type, the result is cached
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+	info := &structInfo{byType: map[uint64]int{}, unknown: -1}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("tlv")
+		if !ok || tag == "-" {
+			continue
+		}
+		f, isUnknown, err := parseTag(sf, tag)
+		if err != nil {
+			return nil, err
+		}
+		f.index = i
+		switch {
+		case sf.Name == "_":
+			info.outer = f.typ
+		case sf.PkgPath != "":
+			return nil, fmt.Errorf("tlv : --- %s.%s: unexported field ---", t, sf.Name)
+		case isUnknown:
+			if sf.Type != reflectTlvSlice {
+				return nil, fmt.Errorf("tlv : --- %s.%s: unknown field must be a []Tlv ---", t, sf.Name)
+			}
+			info.unknown = i
+		default:
+			if _, dup := info.byType[f.typ]; dup {
+				return nil, fmt.Errorf("tlv : --- %s.%s: type 0x%x used twice ---", t, sf.Name, f.typ)
+			}
+			info.byType[f.typ] = len(info.fields)
+			info.fields = append(info.fields, f)
+		}
+	}
+	structInfoCache.Store(t, info)
+	return info, nil
+}
+
+func parseTag(sf reflect.StructField, tag string) (f fieldInfo, isUnknown bool, err error) {
+	parts := strings.Split(tag, ",")
+	f.name = sf.Name
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "optional":
+			f.optional = true
+		case opt == "nonneg":
+		case opt == "unknown":
+			isUnknown = true
+		case strings.HasPrefix(opt, "fixed="):
+			f.fixed, err = strconv.Atoi(strings.TrimPrefix(opt, "fixed="))
+			if err != nil || f.fixed <= 0 {
+				return f, false, fmt.Errorf("tlv : --- %s: bad option %q ---", sf.Name, opt)
+			}
+		default:
+			return f, false, fmt.Errorf("tlv : --- %s: unknown option %q ---", sf.Name, opt)
+		}
+	}
+	if isUnknown {
+		return f, true, nil
+	}
+	f.typ, err = strconv.ParseUint(parts[0], 0, 64)
+	if err != nil || f.typ == 0 || f.typ > 0xFFFFFFFF {
+		return f, false, fmt.Errorf("tlv : --- %s: bad type %q ---", sf.Name, parts[0])
+	}
+	ft := sf.Type
+	f.repeated = ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 && ft != reflectName
+	return f, false, nil
+}
+
+func marshalFields(rv reflect.Value, info *structInfo) ([]*Element, error) {
+	result := []*Element{}
+	for _, f := range info.fields {
+		fv := rv.Field(f.index)
+		if f.repeated {
+			for i := 0; i < fv.Len(); i++ {
+				e, err := marshalValue(fv.Index(i), f)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, e)
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Ptr && fv.IsNil() || fv.Kind() == reflect.Bool && !fv.Bool() {
+			continue
+		}
+		if f.optional && fv.IsZero() {
+			continue
+		}
+		e, err := marshalValue(fv, f)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	if info.unknown >= 0 {
+		for _, t := range rv.Field(info.unknown).Interface().([]Tlv) {
+			result = append(result, NewElement(t.T, t.V))
+		}
+	}
+	return result, nil
+}
+
+func marshalValue(v reflect.Value, f fieldInfo) (*Element, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("Marshal : --- %s: nil element ---", f.name)
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case reflectName:
+		components := []*Element{}
+		for _, c := range v.Interface().(name.Name) {
+			if c.Type == name.AnyType {
+				return nil, fmt.Errorf("Marshal : --- %s: Any can only be used in an Exclude ---", f.name)
+			}
+			components = append(components, NewElement(componentType(c), []byte(c.Value)))
+		}
+		return NewNestedElement(f.typ, components...), nil
+	case reflectComponent:
+		return NewElement(f.typ, []byte(v.Interface().(name.Component).Value)), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return NewElement(f.typ, nil), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b, err := integerBytes(v.Uint(), f)
+		return NewElement(f.typ, b), err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return nil, fmt.Errorf("Marshal : --- %s: negative value ---", f.name)
+		}
+		b, err := integerBytes(uint64(v.Int()), f)
+		return NewElement(f.typ, b), err
+	case reflect.String:
+		return NewElement(f.typ, []byte(v.String())), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if f.fixed != 0 && v.Len() != f.fixed {
+			return nil, fmt.Errorf("Marshal : --- %s: %d bytes, want %d ---", f.name, v.Len(), f.fixed)
+		}
+		return NewElement(f.typ, v.Bytes()), nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return NewElement(f.typ, b), nil
+	case reflect.Struct:
+		info, err := getStructInfo(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		children, err := marshalFields(v, info)
+		if err != nil {
+			return nil, err
+		}
+		return NewNestedElement(f.typ, children...), nil
+	}
+	return nil, fmt.Errorf("Marshal : --- %s: unsupported type %s ---", f.name, v.Type())
+}
+
+//a NonNegativeInteger, or a big endian integer of exactly f.fixed bytes
+func integerBytes(x uint64, f fieldInfo) ([]byte, error) {
+	if f.fixed == 0 {
+		return EncodeNonNegativeInteger(x), nil
+	}
+	if f.fixed > 8 || f.fixed < 8 && x>>(8*uint(f.fixed)) != 0 {
+		return nil, fmt.Errorf("Marshal : --- %s: %d does not fit in %d bytes ---", f.name, x, f.fixed)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	return append([]byte{}, b[8-f.fixed:]...), nil
+}
+
+//parent is the type of the tlv holding the fields, used in errors
+func unmarshalFields(b []byte, rv reflect.Value, info *structInfo, parent uint64) error {
+	tlvs, err := ParseTlvsFromBytes(b)
+	if err != nil {
+		return err
+	}
+	seen := make([]bool, len(info.fields))
+	for _, t := range tlvs {
+		i, ok := info.byType[t.T]
+		if !ok {
+			if IsCritical(t.T) {
+				return &UnknownTypeError{Type: t.T, Parent: parent}
+			}
+			if info.unknown >= 0 {
+				uf := rv.Field(info.unknown)
+				uf.Set(reflect.Append(uf, reflect.ValueOf(Tlv{t.T, t.L, append([]byte{}, t.V...)})))
+			}
+			continue
+		}
+		f := info.fields[i]
+		fv := rv.Field(f.index)
+		if f.repeated {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if err := unmarshalValue(t, elem, f); err != nil {
+				return err
+			}
+			fv.Set(reflect.Append(fv, elem))
+			continue
+		}
+		if seen[i] {
+			return fmt.Errorf("Unmarshal : --- %s: duplicate type 0x%x ---", f.name, t.T)
+		}
+		seen[i] = true
+		if err := unmarshalValue(t, fv, f); err != nil {
+			return err
+		}
+	}
+	for i, f := range info.fields {
+		if !seen[i] && !f.optional && !f.repeated && !optionalKind(rv.Field(f.index).Kind()) {
+			return fmt.Errorf("Unmarshal : --- %s: missing type 0x%x ---", f.name, f.typ)
+		}
+	}
+	return nil
+}
+
+//kinds that can be left out without the optional option
+func optionalKind(k reflect.Kind) bool {
+	return k == reflect.Ptr || k == reflect.Bool
+}
+
+func unmarshalValue(t Tlv, v reflect.Value, f fieldInfo) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := unmarshalValue(t, p.Elem(), f); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	switch v.Type() {
+	case reflectName:
+		//the field type is not always NAME, the value is what matters
+		n, err := decodeName(Tlv{NAME, t.L, t.V}, &DecodeOptions{})
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(n))
+		return nil
+	case reflectComponent:
+		v.Set(reflect.ValueOf(name.ComponentFromBytes(t.V)))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if len(t.V) != 0 {
+			return fmt.Errorf("Unmarshal : --- %s: flag with a value ---", f.name)
+		}
+		v.SetBool(true)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := integerValue(t.V, f)
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return fmt.Errorf("Unmarshal : --- %s: %d overflows %s ---", f.name, x, v.Type())
+		}
+		v.SetUint(x)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := integerValue(t.V, f)
+		if err != nil {
+			return err
+		}
+		if x > 1<<63-1 || v.OverflowInt(int64(x)) {
+			return fmt.Errorf("Unmarshal : --- %s: %d overflows %s ---", f.name, x, v.Type())
+		}
+		v.SetInt(int64(x))
+		return nil
+	case reflect.String:
+		v.SetString(string(t.V))
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if f.fixed != 0 && len(t.V) != f.fixed {
+			return fmt.Errorf("Unmarshal : --- %s: %d bytes, want %d ---", f.name, len(t.V), f.fixed)
+		}
+		v.SetBytes(append([]byte{}, t.V...))
+		return nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if len(t.V) != v.Len() {
+			return fmt.Errorf("Unmarshal : --- %s: %d bytes, want %d ---", f.name, len(t.V), v.Len())
+		}
+		reflect.Copy(v, reflect.ValueOf(t.V))
+		return nil
+	case reflect.Struct:
+		info, err := getStructInfo(v.Type())
+		if err != nil {
+			return err
+		}
+		return unmarshalFields(t.V, v, info, t.T)
+	}
+	return fmt.Errorf("Unmarshal : --- %s: unsupported type %s ---", f.name, v.Type())
+}
+
+func integerValue(b []byte, f fieldInfo) (uint64, error) {
+	if f.fixed != 0 {
+		if len(b) != f.fixed || f.fixed > 8 {
+			return 0, fmt.Errorf("Unmarshal : --- %s: %d bytes, want %d ---", f.name, len(b), f.fixed)
+		}
+		var x [8]byte
+		copy(x[8-len(b):], b)
+		return binary.BigEndian.Uint64(x[:]), nil
+	}
+	switch len(b) {
+	case 1, 2, 4, 8:
+		return DecodeNonNegativeInteger(b), nil
+	}
+	return 0, fmt.Errorf("Unmarshal : --- %s: bad NonNegativeInteger length %d ---", f.name, len(b))
+}
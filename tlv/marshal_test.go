@@ -0,0 +1,29 @@
+package tlv
+
+import "testing"
+
+type missingFields struct {
+	Value []byte `tlv:"0x80"`
+	Hops  []int  `tlv:"0x82"`
+	Flag  bool   `tlv:"0x84"`
+	Ptr   *int   `tlv:"0x86"`
+}
+
+func TestUnmarshalMissingFields(t *testing.T) {
+	//a missing repeated field, bool or pointer is fine
+	var v missingFields
+	if err := Unmarshal([]byte{0x80, 0}, &v); err != nil {
+		t.Errorf("Unmarshal: %v", err)
+	}
+	//[]byte is not repeated, it is always encoded
+	if err := Unmarshal([]byte{}, &v); err == nil {
+		t.Error("Unmarshal: a missing []byte is not an error")
+	}
+	b, err := Marshal(&missingFields{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(b, &v); err != nil {
+		t.Errorf("Unmarshal(Marshal): %v", err)
+	}
+}
@@ -2,10 +2,46 @@ package name
 
 import (
 	"bytes"
+	"encoding/hex"
+	"strconv"
+	"unsafe"
 )
 // name Component is a string
+// the Type is 0 for the generic name components, the other types are the
+// digest components and the Any of the excludes below, or any other component
+// type of the format v0.3 (1 to 65535, such as the segment numbers)
 type Component struct {
 	Value string
+	Type  uint64
+}
+
+//types of the components that are not generic
+const (
+	ImplicitSha256DigestType   = 0x01
+	ParametersSha256DigestType = 0x02
+	AnyType                    = 0x13 //only found in an Exclude
+	genericType                = 0x08
+)
+
+//returns the ParametersSha256DigestComponent holding digest, the sha256 of the application parameters of an interest
+func ParametersSha256DigestComponent(digest []byte) Component {
+	return Component{
+		Value: string(digest),
+		Type:  ParametersSha256DigestType,
+	}
+}
+
+//the tlv type of the component
+func (c Component) GetType() uint64 {
+	if c.Type == 0 {
+		return genericType
+	}
+	return c.Type
+}
+
+//true for a ParametersSha256DigestComponent
+func (c Component) IsParametersDigest() bool {
+	return c.Type == ParametersSha256DigestType
 }
 
 //converts a slice of bytes to a string and returns a component with that value
@@ -15,6 +51,18 @@ func ComponentFromBytes(b []byte) Component {
 	}
 }
 
+//returns a component whose value shares the memory of b instead of copying it.
+//b must not be modified while the component (or anything built from it) is in use,
+//use Detach to get a component that owns its value
+func ComponentFromBytesNoCopy(b []byte) Component {
+	if len(b) == 0 {
+		return Component{}
+	}
+	return Component{
+		Value: *(*string)(unsafe.Pointer(&b)),
+	}
+}
+
 //converts a component to a slice of bytes
 func (c Component) ComponentToBytes() []byte {
 	return []byte(c.Value)
@@ -36,15 +84,50 @@ func (c Component) GetValue() string {
 func (c Component) Copy() Component {
 	return Component{
 		Value: c.Value,
+		Type:  c.Type,
 	}
 }
 
 //Compares 2 components and returns (0: if equal, -1: if c < other, and +1: if c > other)
+//components of different types are ordered by their type
 func (c Component) Compare(other Component) int {
+	if c.GetType() != other.GetType() {
+		if c.GetType() < other.GetType() {
+			return -1
+		}
+		return 1
+	}
 	return bytes.Compare([]byte(c.Value), []byte(other.Value))
 }
 
 // returns a boolean instead of an integer
 func (c Component) Equals(other Component) bool {
-	return c.Value == other.Value
+	return c.GetType() == other.GetType() && c.Value == other.Value
+}
+
+//the component as written in a name, the digest components are written in hex after their prefix
+//and the components of other types after their type number
+func (c Component) ToString() string {
+	switch c.Type {
+	case 0, genericType, AnyType:
+		return c.Value
+	case ImplicitSha256DigestType:
+		return "sha256digest=" + hex.EncodeToString([]byte(c.Value))
+	case ParametersSha256DigestType:
+		return "params-sha256=" + hex.EncodeToString([]byte(c.Value))
+	}
+	return strconv.FormatUint(c.Type, 10) + "=" + c.Value
+}
+
+//returns a component holding its own copy of the value, even if c was built with ComponentFromBytesNoCopy
+func (c Component) Detach() Component {
+	if len(c.Value) == 0 {
+		return c
+	}
+	b := make([]byte, len(c.Value))
+	copy(b, c.Value)
+	return Component{
+		Value: string(b),
+		Type:  c.Type,
+	}
 }
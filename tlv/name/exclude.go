@@ -2,8 +2,9 @@ package name
 
 type Exclude []Component
 
-//the type any is used to match anything
-var Any = Component{}
+//the type any is used to match anything, it has its own type so it is not
+//mistaken for an empty generic component
+var Any = Component{Type: AnyType}
 
 //creates a new name from a number of components
 func NewExclude(cs ...Component) Exclude {
@@ -22,3 +23,8 @@ func (e Exclude) Matches(c Component) bool {
 	}
 	return false
 }
+
+//returns a copy of the exclude where every component owns its value
+func (e Exclude) Detach() Exclude {
+	return Exclude(Name(e).Detach())
+}
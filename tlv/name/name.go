@@ -84,7 +84,19 @@ func (n Name) Equals(other Name) bool {
 func (n Name) ToString() string {
 	stringComponents := []string{}
 	for _, c := range n {
-		stringComponents = append(stringComponents, c.GetValue())
+		stringComponents = append(stringComponents, c.ToString())
 	}
 	return fmt.Sprintf("/%s", strings.Join(stringComponents, "/"))
 }
+
+//returns a copy of the name where every component owns its value
+func (n Name) Detach() Name {
+	if n == nil {
+		return nil
+	}
+	result := make(Name, len(n))
+	for i, c := range n {
+		result[i] = c.Detach()
+	}
+	return result
+}
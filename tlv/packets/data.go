@@ -76,3 +76,13 @@ func (d Data) GetSignature() Signature {
 func (d *Data) SetSignature(s Signature) {
 	d.signature = s
 }
+
+//returns a deep copy of the data that does not reference the buffer it was decoded from
+func (d Data) Detach() Data {
+	d.name = d.name.Detach()
+	d.MetaInfo = d.MetaInfo.Detach()
+	d.content = copyBytes(d.content)
+	d.signature = d.signature.Detach()
+	d.buffer = copyBytes(d.buffer)
+	return d
+}
@@ -11,17 +11,53 @@ import (
 // Interest ::= INTEREST-TYPE TLV-LENGTH
 //					Name
 //					Selectors?
+//					CanBePrefix?
+//					MustBeFresh?
+//					ForwardingHint?
 //					Nonce
 //					InterestLifetime?
-//					ForwardingHint?
+//					HopLimit?
+//					ApplicationParameters?
+// Selectors come from the format v0.2, CanBePrefix, MustBeFresh, ForwardingHint, HopLimit
+// and ApplicationParameters from the format v0.3. an interest is written in a single format,
+// ToFormat maps the fields of one format onto the other
 type Interest struct {
-	Arr         time.Time
-	name        name.Name
-	Selector    Selectors
-	nonce       [4]byte
-	hasLifetime bool
-	lifetime    time.Duration
-	buffer      []byte
+	Arr                      time.Time
+	format                   InterestFormat
+	name                     name.Name
+	Selector                 Selectors
+	canBePrefix              bool
+	mustBeFresh              bool
+	forwardingHint           []name.Name
+	nonce                    [4]byte
+	hasLifetime              bool
+	lifetime                 time.Duration
+	HasHopLimit              bool
+	hopLimit                 uint8
+	HasApplicationParameters bool
+	applicationParameters    []byte
+	buffer                   []byte
+}
+
+//the packet format of an interest
+type InterestFormat int
+
+const (
+	//the interest was not decoded: it is in the format of the fields that are set,
+	//v0.2 if it has selectors and v0.3 otherwise
+	InterestFormatUnset InterestFormat = iota
+	InterestFormatV02
+	InterestFormatV03
+)
+
+func (f InterestFormat) String() string {
+	switch f {
+	case InterestFormatV02:
+		return "v0.2"
+	case InterestFormatV03:
+		return "v0.3"
+	}
+	return "unset"
 }
 
 func NewInterest(name name.Name) *Interest {
@@ -38,6 +74,59 @@ func (i Interest) PacketType() uint64 {
 }
 
 // getters and setters
+
+//the format the interest was decoded from, or the one of its fields (see InterestFormatUnset)
+func (i Interest) GetFormat() InterestFormat {
+	if i.format != InterestFormatUnset {
+		return i.format
+	}
+	if !i.Selector.IsEmpty() {
+		return InterestFormatV02
+	}
+	return InterestFormatV03
+}
+
+//records the format of the interest without changing its fields, use ToFormat to translate them
+func (i *Interest) SetFormat(f InterestFormat) {
+	i.format = f
+}
+
+//gives back the interest in the format f, the fields of its own format being mapped onto
+//the ones of f:
+//  - CanBePrefix is false exactly when MaxSuffixComponents is 1 (the data name can only add
+//    the implicit digest), a v0.2 interest without MaxSuffixComponents matches longer names
+//  - the MustBeFresh of the interest is the one of the selectors
+//the other selectors (MinSuffixComponents, PublisherPublicKeyLocator, Exclude, ChildSelector)
+//have no v0.3 counterpart and are dropped, as are the ForwardingHint and HopLimit going to
+//v0.2. the ApplicationParameters are kept, a v0.2 interest can not be encoded with them.
+//a v0.2 interest is encoded with its Selectors even when they are empty, so a decoder
+//reads it as v0.2 and a CanBePrefix interest is not taken for an exact match
+func (i Interest) ToFormat(f InterestFormat) Interest {
+	from := i.GetFormat()
+	if f == InterestFormatUnset || f == from {
+		return i
+	}
+	switch f {
+	case InterestFormatV03:
+		sel := i.Selector
+		i.canBePrefix = !(sel.HasMaxSuffixComponents && sel.GetMaxSuffixComponents() == 1)
+		i.mustBeFresh = sel.GetMustBeFresh()
+		i.Selector = Selectors{}
+	case InterestFormatV02:
+		i.Selector = Selectors{}
+		if !i.canBePrefix {
+			i.Selector.SetMaxSuffixComponents(1)
+		}
+		i.Selector.SetMustBeFresh(i.mustBeFresh)
+		i.canBePrefix = false
+		i.mustBeFresh = false
+		i.forwardingHint = nil
+		i.HasHopLimit = false
+		i.hopLimit = 0
+	}
+	i.format = f
+	return i
+}
 func (i Interest) GetName() name.Name {
 	if i.name == nil {
 		i.name = name.Name{}
@@ -69,6 +158,58 @@ func (i *Interest) SetInterestLifetime(x time.Duration) {
 	i.lifetime = x
 }
 
+func (i Interest) GetCanBePrefix() bool {
+	return i.canBePrefix
+}
+
+func (i *Interest) SetCanBePrefix(x bool) {
+	i.canBePrefix = x
+}
+
+//the MustBeFresh of the interest itself (format v0.3), the one of the selectors is Selector.GetMustBeFresh
+func (i Interest) GetMustBeFresh() bool {
+	return i.mustBeFresh
+}
+
+func (i *Interest) SetMustBeFresh(x bool) {
+	i.mustBeFresh = x
+}
+
+// ForwardingHint ::= FORWARDING-HINT-TYPE TLV-LENGTH 1*Name
+func (i Interest) GetForwardingHint() []name.Name {
+	return i.forwardingHint
+}
+
+func (i *Interest) SetForwardingHint(hint []name.Name) {
+	i.forwardingHint = hint
+}
+
+func (i Interest) GetHopLimit() uint8 {
+	if !i.HasHopLimit {
+		return 0
+	}
+	return i.hopLimit
+}
+
+func (i *Interest) SetHopLimit(x uint8) {
+	i.HasHopLimit = true
+	i.hopLimit = x
+}
+
+//the parameters are sent along with the name, the encoder adds the
+//ParametersSha256DigestComponent they need to the name
+func (i Interest) GetApplicationParameters() []byte {
+	if !i.HasApplicationParameters {
+		return nil
+	}
+	return i.applicationParameters
+}
+
+func (i *Interest) SetApplicationParameters(p []byte) {
+	i.HasApplicationParameters = true
+	i.applicationParameters = p
+}
+
 func (i Interest) GetNonce() [4]byte {
 	return i.nonce
 }
@@ -89,3 +230,19 @@ func nonceToBytes(n int32) [4]byte {
 	binary.BigEndian.PutUint32(b[:], uint32(n))
 	return b
 }
+
+//returns a deep copy of the interest that does not reference the buffer it was decoded from
+func (i Interest) Detach() Interest {
+	i.name = i.name.Detach()
+	i.Selector = i.Selector.Detach()
+	if i.forwardingHint != nil {
+		hint := make([]name.Name, len(i.forwardingHint))
+		for j, n := range i.forwardingHint {
+			hint[j] = n.Detach()
+		}
+		i.forwardingHint = hint
+	}
+	i.applicationParameters = copyBytes(i.applicationParameters)
+	i.buffer = copyBytes(i.buffer)
+	return i
+}
@@ -40,3 +40,9 @@ func (kl KeyLocator) SetKeyDigest(kd []byte) {
 func (kl KeyLocator) IsEmpty() bool {
 	return kl.Name == nil && kl.KeyDigest == nil
 }
+
+func (kl KeyLocator) Detach() KeyLocator {
+	kl.Name = kl.Name.Detach()
+	kl.KeyDigest = copyBytes(kl.KeyDigest)
+	return kl
+}
@@ -68,3 +68,8 @@ func (m *MetaInfo) SetFinalBlockID(id name.Component) {
 	m.hasFinalBlockID = true
 	m.finalBlockID = id.Copy()
 }
+
+func (m MetaInfo) Detach() MetaInfo {
+	m.finalBlockID = m.finalBlockID.Detach()
+	return m
+}
@@ -8,3 +8,29 @@ package packets
 type NdnPacket interface {
 	PacketType() uint64
 }
+
+//returns a copy of a decoded packet that shares no memory with the buffer it was decoded from
+func Detach(p NdnPacket) NdnPacket {
+	switch x := p.(type) {
+	case Interest:
+		return x.Detach()
+	case *Interest:
+		d := x.Detach()
+		return &d
+	case Data:
+		return x.Detach()
+	case *Data:
+		d := x.Detach()
+		return &d
+	default:
+		return p
+	}
+}
+
+//copy of b, nil stays nil
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,59 @@
+package packets
+
+import (
+	"sync"
+
+	"ndn-router/nfd/tlv/name"
+)
+
+var interestPool = sync.Pool{
+	New: func() interface{} {
+		return new(Interest)
+	},
+}
+
+var dataPool = sync.Pool{
+	New: func() interface{} {
+		return new(Data)
+	},
+}
+
+//takes an empty interest from the pool, it has to be given back with Release
+func AcquireInterest() *Interest {
+	return interestPool.Get().(*Interest)
+}
+
+//resets the interest and puts it back in the pool.
+//neither the interest nor anything taken from it (name, buffer ...) may be used afterwards,
+//the memory of the name is reused by the next interest taken from the pool
+func (i *Interest) Release() {
+	n := clearName(i.name)
+	*i = Interest{}
+	i.name = n
+	interestPool.Put(i)
+}
+
+//takes an empty data from the pool, it has to be given back with Release
+func AcquireData() *Data {
+	return dataPool.Get().(*Data)
+}
+
+//resets the data and puts it back in the pool.
+//neither the data nor anything taken from it (name, content ...) may be used afterwards,
+//the memory of the name is reused by the next data taken from the pool
+func (d *Data) Release() {
+	n := clearName(d.name)
+	*d = Data{}
+	d.name = n
+	dataPool.Put(d)
+}
+
+//empties the name but keeps its memory, the components are cleared so the
+//pool does not keep the old values alive
+func clearName(n name.Name) name.Name {
+	full := n[:cap(n)]
+	for i := range full {
+		full[i] = name.Component{}
+	}
+	return full[:0]
+}
@@ -24,7 +24,7 @@ type Selectors struct {
 }
 
 func (sel Selectors) IsEmpty() bool {
-	return !(sel.HasMinSuffixComponents && sel.HasMaxSuffixComponents && sel.HasPublisherPublicKeyLocator && sel.HasChildSelector && sel.HasExclude && sel.mustBeFresh)
+	return !(sel.HasMinSuffixComponents || sel.HasMaxSuffixComponents || sel.HasPublisherPublicKeyLocator || sel.HasChildSelector || sel.HasExclude || sel.mustBeFresh)
 }
 
 func (sel Selectors) GetMinSuffixComponents() uint64 {
@@ -94,3 +94,9 @@ func (sel Selectors) GetMustBeFresh() bool {
 func (sel *Selectors) SetMustBeFresh(mbf bool) {
 	sel.mustBeFresh = mbf
 }
+
+func (sel Selectors) Detach() Selectors {
+	sel.publisherPublicKeyLocator = sel.publisherPublicKeyLocator.Detach()
+	sel.exclude = sel.exclude.Detach()
+	return sel
+}
@@ -37,3 +37,8 @@ func (si SignatureInfo) GetKeyLocator() KeyLocator {
 	}
 	return si.keyLoc
 }
+
+func (si SignatureInfo) Detach() SignatureInfo {
+	si.keyLoc = si.keyLoc.Detach()
+	return si
+}
@@ -29,3 +29,9 @@ func (s Signature) SetsigVal(val []byte) {
 func (s Signature) GetsigVal() []byte {
 	return s.val
 }
+
+func (s Signature) Detach() Signature {
+	s.sigInfo = s.sigInfo.Detach()
+	s.val = copyBytes(s.val)
+	return s
+}
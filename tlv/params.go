@@ -0,0 +1,133 @@
+package tlv
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"ndn-router/nfd/tlv/name"
+	"ndn-router/nfd/tlv/packets"
+)
+
+//an interest with ApplicationParameters (format v0.3) holds a ParametersSha256DigestComponent
+//in its name: the sha256 of the ApplicationParameters tlv and of every field after it.
+//an interest without parameters holds no such component.
+//the encoder computes the component, the decoder and Validate check it
+
+//checks the digest component of the name of the interest t against its parameters, t being
+//known to be well formed. offsets are relative to the value of t
+func checkParametersDigest(t Tlv) error {
+	nameEnd, digestAt, paramsAt, digest, err := findParametersDigest(t)
+	if err != nil {
+		return err
+	}
+	switch {
+	case paramsAt < 0 && digestAt < 0:
+		return nil
+	case paramsAt < 0:
+		return &TlvError{Type: PARAMETERS_SHA256_DIGEST, Offset: digestAt, Err: ErrParametersDigest}
+	case digestAt < 0:
+		//reported at the end of the name, where the component is usually found
+		return &TlvError{Type: PARAMETERS_SHA256_DIGEST, Offset: nameEnd, Err: ErrMissingField}
+	}
+	sum := sha256.Sum256(t.V[paramsAt:])
+	if !bytes.Equal(sum[:], digest) {
+		return &TlvError{Type: PARAMETERS_SHA256_DIGEST, Offset: digestAt, Err: ErrParametersDigest}
+	}
+	return nil
+}
+
+//writes the digest of the parameters of the interest t in the digest component of its name,
+//when it has both. the value of t is changed in place
+func setParametersDigest(t Tlv) error {
+	_, digestAt, paramsAt, digest, err := findParametersDigest(t)
+	if err != nil || digestAt < 0 || paramsAt < 0 || len(digest) != sha256.Size {
+		return err
+	}
+	sum := sha256.Sum256(t.V[paramsAt:])
+	copy(digest, sum[:])
+	return nil
+}
+
+//finds the end of the name of the interest t, the offsets of its ParametersSha256DigestComponent
+//and of its ApplicationParameters (-1 when they are not there) and the value of the component.
+//offsets are relative to the value of t
+func findParametersDigest(t Tlv) (nameEnd, digestAt, paramsAt int, digest []byte, err error) {
+	var nameTlv Tlv
+	nameAt := -1
+	digestAt, paramsAt = -1, -1
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return 0, 0, 0, nil, errorAt(err, i)
+		}
+		switch f.T {
+		case NAME:
+			nameTlv, nameAt = f, i+ts+ls
+		case APPLICATION_PARAMETERS:
+			paramsAt = i
+		}
+		i += ts + ls + int(f.L)
+	}
+	for i := 0; nameAt >= 0 && i < len(nameTlv.V); {
+		c, err, ts, ls := TlvFromBytes(nameTlv.V[i:])
+		if err != nil {
+			return 0, 0, 0, nil, errorAt(err, nameAt+i)
+		}
+		if c.T == PARAMETERS_SHA256_DIGEST {
+			if digestAt >= 0 {
+				return 0, 0, 0, nil, &TlvError{Type: c.T, Offset: nameAt + i, Err: ErrDuplicateField}
+			}
+			digestAt, digest = nameAt+i, c.V
+		}
+		i += ts + ls + int(c.L)
+	}
+	return nameAt + len(nameTlv.V), digestAt, paramsAt, digest, nil
+}
+
+//true if the name holds a ParametersSha256DigestComponent
+func hasParametersDigest(nm name.Name) bool {
+	for _, comp := range nm {
+		if comp.IsParametersDigest() {
+			return true
+		}
+	}
+	return false
+}
+
+//the name of an interest is encoded without its digest components, and with a single one
+//when it has parameters: in place of the first one it had or at the end of the name
+func interestNameLength(i packets.Interest) int {
+	l := 0
+	for _, comp := range i.GetName() {
+		if !comp.IsParametersDigest() {
+			l += componentSize(comp)
+		}
+	}
+	if i.HasApplicationParameters {
+		l += tlvSize(PARAMETERS_SHA256_DIGEST, sha256.Size)
+	}
+	return l
+}
+
+//writes the name of the interest and gives back the number of bytes written and the offset
+//of the value of the digest component in b (-1 without parameters). the value is left for
+//writeInterest to fill once the parameters are written
+func writeInterestName(b []byte, i packets.Interest) (int, int) {
+	n := writeHeader(b, NAME, interestNameLength(i))
+	digestAt := -1
+	for _, comp := range i.GetName() {
+		if !comp.IsParametersDigest() {
+			n += writeNameComponent(b[n:], comp)
+		} else if i.HasApplicationParameters && digestAt < 0 {
+			n += writeHeader(b[n:], PARAMETERS_SHA256_DIGEST, sha256.Size)
+			digestAt = n
+			n += sha256.Size
+		}
+	}
+	if i.HasApplicationParameters && digestAt < 0 {
+		n += writeHeader(b[n:], PARAMETERS_SHA256_DIGEST, sha256.Size)
+		digestAt = n
+		n += sha256.Size
+	}
+	return n, digestAt
+}
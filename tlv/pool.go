@@ -0,0 +1,109 @@
+package tlv
+
+import (
+	"sync"
+
+	"ndn-router/nfd/tlv/packets"
+)
+
+//scratch []Tlv slices, they only live while a packet is being decoded
+var tlvsPool = sync.Pool{
+	New: func() interface{} {
+		s := make([]Tlv, 0, 8)
+		return &s
+	},
+}
+
+func getTlvs() *[]Tlv {
+	return tlvsPool.Get().(*[]Tlv)
+}
+
+//parses b into the pooled slice s, which keeps the grown slice so it goes back to the pool
+func parseInto(s *[]Tlv, b []byte, maxSize int, canonical bool) ([]Tlv, error) {
+	var err error
+	*s, err = parseTlvs((*s)[:0], b, maxSize, canonical)
+	return *s, err
+}
+
+//the tlvs are cleared first so the pool does not keep old packets alive
+func putTlvs(s *[]Tlv) {
+	full := (*s)[:cap(*s)]
+	for i := range full {
+		full[i] = Tlv{}
+	}
+	*s = full[:0]
+	tlvsPool.Put(s)
+}
+
+//Buffer holds an encoded packet, it comes from a pool and has to be given
+//back with Release once the bytes are not needed anymore
+type Buffer struct {
+	b []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{b: make([]byte, 0, MaxPacketSize)}
+	},
+}
+
+//the encoded packet, only valid until Release is called
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+//puts the buffer back in the pool, neither the buffer nor its bytes may be used afterwards
+func (b *Buffer) Release() {
+	b.b = b.b[:0]
+	bufferPool.Put(b)
+}
+
+//encodes the packet into a buffer taken from the pool
+func EncodePooled(packet packets.NdnPacket) (*Buffer, error) {
+	size, err := EncodedLength(packet)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufferPool.Get().(*Buffer)
+	if cap(buf.b) < size {
+		buf.b = make([]byte, size)
+	}
+	buf.b = buf.b[:size]
+	if _, err := EncodeTo(packet, buf.b); err != nil {
+		buf.Release()
+		return nil, err
+	}
+	return buf, nil
+}
+
+//decodes into an interest or a data taken from the pool, the result is a *packets.Interest
+//or a *packets.Data that has to be given back with its Release() method once it is not used anymore.
+//the ownership rules of DecodeWithOptions apply, and nothing taken from the packet
+//(name, content ...) may be used after Release since it will be reused by the next packets.
+//the errors are the ones of Decode, a packet that could not be decoded is released before returning
+func DecodePooled(packet []byte, opts DecodeOptions) (packets.NdnPacket, error) {
+	t, err := opts.readPacket(packet)
+	if err != nil {
+		return nil, err
+	}
+	switch t.T {
+	case INTEREST:
+		resultInterest := packets.AcquireInterest()
+		if err := decodeInterestInto(t, &opts, resultInterest); err != nil {
+			resultInterest.Release()
+			return nil, locate(err, packet)
+		}
+		resultInterest.Setbuffer(packet)
+		return resultInterest, nil
+	case DATA:
+		resultData := packets.AcquireData()
+		if err := decodeDataInto(t, &opts, resultData); err != nil {
+			resultData.Release()
+			return nil, locate(err, packet)
+		}
+		resultData.Setbuffer(packet)
+		return resultData, nil
+	default:
+		return nil, unknownPacketError(t.T)
+	}
+}
@@ -0,0 +1,104 @@
+package tlv
+
+import (
+	"bytes"
+	"testing"
+
+	"ndn-router/nfd/tlv/packets"
+)
+
+//the interest of examples/testPooledCodec.go: a 3 component name, selectors, nonce and lifetime
+var benchInterest = []byte{
+	0x05, 39,
+	0x07, 15,
+	0x08, 3, 'f', 'o', 'o',
+	0x08, 3, 'b', 'a', 'r',
+	0x08, 3, 'b', 'a', 'z',
+	0x09, 10,
+	0x0d, 1, 1,
+	0x10, 5, 0x08, 1, 'a', 0x13, 0,
+	0x0a, 4, 'a', 'b', 'c', 'd',
+	0x0c, 2, 0x03, 0xe8,
+}
+
+func TestEncodePooledAllocs(t *testing.T) {
+	interest, err := Decode(benchInterest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, err := EncodePooled(interest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Release()
+	})
+	if allocs != 0 {
+		t.Errorf("EncodePooled: %v allocs, want 0", allocs)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(benchInterest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodePooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p, err := DecodePooled(benchInterest, DecodeOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.(*packets.Interest).Release()
+	}
+}
+
+func BenchmarkDecodePooledZeroCopy(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p, err := DecodePooled(benchInterest, DecodeOptions{ZeroCopy: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.(*packets.Interest).Release()
+	}
+}
+
+func BenchmarkEncodeToBytes(b *testing.B) {
+	interest, _ := Decode(benchInterest)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeToBytes(interest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodePooled(b *testing.B) {
+	interest, _ := Decode(benchInterest)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err := EncodePooled(interest)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf.Release()
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	interest, _ := Decode(benchInterest)
+	var w bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Reset()
+		if err := Encode(interest, &w); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
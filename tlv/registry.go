@@ -0,0 +1,205 @@
+package tlv
+
+import (
+	"fmt"
+	"sync"
+)
+
+//ValueKind says what the value of a tlv type holds
+type ValueKind int
+
+const (
+	KindBytes              ValueKind = iota //opaque bytes
+	KindNested                              //a list of tlvs
+	KindNonNegativeInteger                  //a NonNegativeInteger
+	KindString                              //UTF-8 text
+	KindFlag                                //nothing, the tlv is present or not
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case KindBytes:
+		return "bytes"
+	case KindNested:
+		return "nested"
+	case KindNonNegativeInteger:
+		return "nonneg"
+	case KindString:
+		return "string"
+	case KindFlag:
+		return "flag"
+	}
+	return fmt.Sprintf("ValueKind(%d)", int(k))
+}
+
+//TypeInfo describes a tlv type known by the registry.
+//the name is the one of the constant (FRESHNESS_PERIOD), it is used by the error messages,
+//Diff, the text notation and the Element tree. Decode is optional, it turns a value into
+//a Go value for DecodeValue when the one given by the kind is not the right one
+type TypeInfo struct {
+	Type   uint64
+	Name   string
+	Kind   ValueKind
+	Decode func(t Tlv) (interface{}, error)
+}
+
+//the types of constants.go, the generated codecs register their own types
+var builtinTypes = []TypeInfo{
+	{Type: INTEREST, Name: "INTEREST", Kind: KindNested},
+	{Type: DATA, Name: "DATA", Kind: KindNested},
+	{Type: NAME, Name: "NAME", Kind: KindNested},
+	{Type: NAME_COMPONENT, Name: "NAME_COMPONENT", Kind: KindBytes},
+	{Type: IMPLICIT_DIGEST, Name: "IMPLICIT_DIGEST", Kind: KindBytes},
+	{Type: PARAMETERS_SHA256_DIGEST, Name: "PARAMETERS_SHA256_DIGEST", Kind: KindBytes},
+	{Type: SELECTORS, Name: "SELECTORS", Kind: KindNested},
+	{Type: NONCE, Name: "NONCE", Kind: KindBytes},
+	{Type: INTEREST_LIFETIME, Name: "INTEREST_LIFETIME", Kind: KindNonNegativeInteger},
+	{Type: CAN_BE_PREFIX, Name: "CAN_BE_PREFIX", Kind: KindFlag},
+	{Type: FORWARDING_HINT, Name: "FORWARDING_HINT", Kind: KindNested},
+	{Type: HOP_LIMIT, Name: "HOP_LIMIT", Kind: KindBytes, Decode: fixedIntegerDecoder(1)},
+	{Type: APPLICATION_PARAMETERS, Name: "APPLICATION_PARAMETERS", Kind: KindBytes},
+	{Type: MIN_SUFFIX_COMPONENTS, Name: "MIN_SUFFIX_COMPONENTS", Kind: KindNonNegativeInteger},
+	{Type: MAX_SUFFIX_COMPONENTS, Name: "MAX_SUFFIX_COMPONENTS", Kind: KindNonNegativeInteger},
+	{Type: PUBLISHER_PUB_KEY_LOCATOR, Name: "PUBLISHER_PUB_KEY_LOCATOR", Kind: KindNested},
+	{Type: EXCLUDE, Name: "EXCLUDE", Kind: KindNested},
+	{Type: CHILD_SELECTOR, Name: "CHILD_SELECTOR", Kind: KindNonNegativeInteger},
+	{Type: MUST_BE_FRESH, Name: "MUST_BE_FRESH", Kind: KindFlag},
+	{Type: ANY, Name: "ANY", Kind: KindFlag},
+	{Type: META_INFO, Name: "META_INFO", Kind: KindNested},
+	{Type: CONTENT, Name: "CONTENT", Kind: KindBytes},
+	{Type: SIGNATURE_INFO, Name: "SIGNATURE_INFO", Kind: KindNested},
+	{Type: SIGNATURE_VALUE, Name: "SIGNATURE_VALUE", Kind: KindBytes},
+	{Type: CONTENT_TYPE, Name: "CONTENT_TYPE", Kind: KindNonNegativeInteger},
+	{Type: FRESHNESS_PERIOD, Name: "FRESHNESS_PERIOD", Kind: KindNonNegativeInteger},
+	{Type: FINAL_BLOCK_ID, Name: "FINAL_BLOCK_ID", Kind: KindNested},
+	{Type: SIGNATURE_TYPE, Name: "SIGNATURE_TYPE", Kind: KindNonNegativeInteger},
+	{Type: KEY_LOCATOR, Name: "KEY_LOCATOR", Kind: KindNested},
+	{Type: KEY_DIGEST, Name: "KEY_DIGEST", Kind: KindBytes},
+	{Type: VALIDITY_PERIOD, Name: "VALIDITY_PERIOD", Kind: KindNested},
+}
+
+var registry = struct {
+	sync.RWMutex
+	byType map[uint64]TypeInfo
+	byName map[string]uint64
+}{
+	byType: map[uint64]TypeInfo{},
+	byName: map[string]uint64{},
+}
+
+func init() {
+	for _, info := range builtinTypes {
+		mustRegisterType(info)
+	}
+}
+
+//RegisterType adds an application type to the registry, so it is shown by its name and
+//its value is read according to its kind. a type number or a name can only be registered once
+func RegisterType(info TypeInfo) error {
+	if info.Type == 0 || info.Type > 0xFFFFFFFF {
+		return fmt.Errorf("RegisterType : --- bad type 0x%x ---", info.Type)
+	}
+	if !isTypeName(info.Name) {
+		return fmt.Errorf("RegisterType : --- bad name %q ---", info.Name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if old, ok := registry.byType[info.Type]; ok {
+		return fmt.Errorf("RegisterType : --- type 0x%x already registered as %s ---", info.Type, old.Name)
+	}
+	if t, ok := registry.byName[info.Name]; ok {
+		return fmt.Errorf("RegisterType : --- name %s already registered for type 0x%x ---", info.Name, t)
+	}
+	registry.byType[info.Type] = info
+	registry.byName[info.Name] = info.Type
+	return nil
+}
+
+//for the types of the package, that must not conflict
+func mustRegisterType(info TypeInfo) {
+	if err := RegisterType(info); err != nil {
+		panic(err)
+	}
+}
+
+//a name can be written in the text notation: letters, digits and '_', not starting with a digit
+func isTypeName(s string) bool {
+	for i, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+//LookupType gives back what the registry knows about the type t
+func LookupType(t uint64) (TypeInfo, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	info, ok := registry.byType[t]
+	return info, ok
+}
+
+//LookupTypeName gives back the type registered with the name n
+func LookupTypeName(n string) (TypeInfo, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.byName[n]
+	if !ok {
+		return TypeInfo{}, false
+	}
+	return registry.byType[t], true
+}
+
+//TypeName gives back the registered name of t, or t in hex if it is not registered
+func TypeName(t uint64) string {
+	if info, ok := LookupType(t); ok {
+		return info.Name
+	}
+	return fmt.Sprintf("0x%x", t)
+}
+
+//DecodeValue reads the value of t with the decoder of its registered type, or according
+//to its kind when it has none: a uint64 for a NonNegativeInteger, a string, a bool for
+//a flag, the []Tlv of a nested tlv and the []byte of the value otherwise
+func DecodeValue(t Tlv) (interface{}, error) {
+	info, ok := LookupType(t.T)
+	if !ok {
+		return t.V, nil
+	}
+	if info.Decode != nil {
+		return info.Decode(t)
+	}
+	switch info.Kind {
+	case KindNested:
+		return ParseTlvsFromBytes(t.V)
+	case KindNonNegativeInteger:
+		return DecodeNonNegativeIntegerValue(t)
+	case KindString:
+		return DecodeStringValue(t)
+	case KindFlag:
+		return DecodeFlagValue(t)
+	}
+	return t.V, nil
+}
+
+//true if t is registered with the kind k
+func isKind(t uint64, k ValueKind) bool {
+	info, ok := LookupType(t)
+	return ok && info.Kind == k
+}
+
+//decoder of the big endian integers of exactly size bytes held by some of the generated types
+func fixedIntegerDecoder(size int) func(t Tlv) (interface{}, error) {
+	return func(t Tlv) (interface{}, error) {
+		v, err := DecodeFixedValue(t, size)
+		if err != nil {
+			return nil, err
+		}
+		x := uint64(0)
+		for _, b := range v {
+			x = x<<8 | uint64(b)
+		}
+		return x, nil
+	}
+}
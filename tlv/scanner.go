@@ -0,0 +1,134 @@
+package tlv
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"ndn-router/nfd/tlv/packets"
+)
+
+//FileScanner goes through a file of back-to-back encoded packets (a capture, recorded
+//test traffic ...) and decodes them one at a time, the file is streamed so it can be
+//bigger than the memory:
+//
+//	s, err := tlv.NewFileScanner("traffic.bin")
+//	...
+//	defer s.Close()
+//	for s.Scan() {
+//		handle(s.Offset(), s.Packet())
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+//a packet that is correctly framed but cannot be decoded stops the scan, or is skipped
+//when SkipInvalid is set. broken framing always stops the scan: tlvs have no marker
+//to find the start of the next packet
+type FileScanner struct {
+	//undecodable packets are skipped and counted instead of stopping the scan
+	SkipInvalid bool
+	//options used to decode the packets
+	Options DecodeOptions
+
+	f       *os.File
+	r       *Reader
+	packet  packets.NdnPacket
+	wire    []byte
+	offset  int64
+	skipped int
+	err     error
+}
+
+//opens the file at path for scanning, the file must be closed with Close
+func NewFileScanner(path string) (*FileScanner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileScanner{f: f}, nil
+}
+
+//moves to the next packet, false once the end of the file is reached or the scan stopped
+func (s *FileScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	s.packet, s.wire = nil, nil
+	if s.r == nil {
+		//created on the first scan so the options can be set before
+		s.r = NewReaderSize(s.f, s.Options.packetLimit())
+	}
+	for {
+		wire, err := s.r.ReadWire()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			s.err = err
+			return false
+		}
+		offset := s.r.offset - int64(len(wire))
+		//the packet is kept after the next read, it gets its own copy of the bytes
+		wire = append([]byte(nil), wire...)
+		opts := s.Options
+		p, err := decodePacket(wire, &opts)
+		if err != nil {
+			if s.SkipInvalid {
+				s.skipped++
+				continue
+			}
+			s.err = scanError(err, wire, offset)
+			return false
+		}
+		s.packet, s.wire, s.offset = p, wire, offset
+		return true
+	}
+}
+
+//gives the decode error an offset in the file
+func scanError(err error, wire []byte, offset int64) error {
+	var d *DecodeError
+	if errors.As(err, &d) {
+		if d.Offset >= 0 {
+			d.Offset += int(offset)
+		}
+		return d
+	}
+	var e *TlvError
+	if errors.As(err, &e) {
+		return errorAt(err, int(offset))
+	}
+	t, _, _, _, _ := readHeader(wire, false)
+	return &TlvError{Type: t, Offset: int(offset), Err: err}
+}
+
+//the packet read by the last call to Scan
+func (s *FileScanner) Packet() packets.NdnPacket {
+	return s.packet
+}
+
+//the wire encoding of the packet read by the last call to Scan
+func (s *FileScanner) Wire() []byte {
+	return s.wire
+}
+
+//offset of the packet in the file
+func (s *FileScanner) Offset() int64 {
+	return s.offset
+}
+
+//number of undecodable packets skipped so far
+func (s *FileScanner) Skipped() int {
+	return s.skipped
+}
+
+//the error that stopped the scan, nil if the end of the file was reached.
+//the offset of a *TlvError or a *DecodeError is counted from the start of the file
+func (s *FileScanner) Err() error {
+	return s.err
+}
+
+func (s *FileScanner) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,166 @@
+package tlv
+
+import "ndn-router/nfd/tlv/packets"
+
+//the schema of a nested tlv is the list of the fields it can hold, in the order they must
+//appear. Validate walks a tlv with the checks of its rules, Decode with their decoders
+
+//a field a tlv can hold. check is nil when any value is fine, decode sets the field in
+//the packet being decoded and is nil for the fields that are recognized but not kept
+type fieldRule struct {
+	typ        uint64
+	required   bool
+	repeatable bool
+	check      func(t Tlv) error
+	decode     func(packet interface{}, t Tlv, opts *DecodeOptions) error
+}
+
+//the fields of each tlv, in the order they must appear.
+//the interest has a table per format, interestRulesOf picks the one of a packet.
+//the Nonce is optional in v0.3 but a forwarder needs it, it is required in both formats
+var (
+	interestV02Rules = []fieldRule{
+		{NAME, true, false, validateName, decodeInterestName},
+		{SELECTORS, false, false, validateSelectors, decodeInterestSelectors},
+		{NONCE, true, false, checkNonce, decodeInterestNonce},
+		{INTEREST_LIFETIME, false, false, checkNonNegativeInteger, decodeInterestLifeTime},
+	}
+	interestV03Rules = []fieldRule{
+		{NAME, true, false, validateName, decodeInterestName},
+		{CAN_BE_PREFIX, false, false, checkFlag, decodeInterestCanBePrefix},
+		{MUST_BE_FRESH, false, false, checkFlag, decodeInterestMustBeFresh},
+		{FORWARDING_HINT, false, false, validateForwardingHint, decodeInterestForwardingHint},
+		{NONCE, true, false, checkNonce, decodeInterestNonce},
+		{INTEREST_LIFETIME, false, false, checkNonNegativeInteger, decodeInterestLifeTime},
+		{HOP_LIMIT, false, false, checkHopLimit, decodeInterestHopLimit},
+		{APPLICATION_PARAMETERS, false, false, nil, decodeInterestApplicationParameters},
+	}
+	forwardingHintRules = []fieldRule{
+		{NAME, true, true, validateName, decodeForwardingHintName},
+	}
+	dataRules = []fieldRule{
+		{NAME, true, false, validateName, decodeDataName},
+		{META_INFO, false, false, validateMetaInfo, decodeDataMetaInfo},
+		{CONTENT, false, false, nil, decodeDataContent},
+		{SIGNATURE_INFO, true, false, validateSignatureInfo, decodeDataSignatureInfo},
+		{SIGNATURE_VALUE, true, false, nil, decodeDataSignatureValue},
+	}
+	selectorRules = []fieldRule{
+		{MIN_SUFFIX_COMPONENTS, false, false, checkNonNegativeInteger, decodeSelectorField},
+		{MAX_SUFFIX_COMPONENTS, false, false, checkNonNegativeInteger, decodeSelectorField},
+		{PUBLISHER_PUB_KEY_LOCATOR, false, false, validatePublisherPublicKeyLocator, decodeSelectorField},
+		{EXCLUDE, false, false, validateExclude, decodeSelectorField},
+		{CHILD_SELECTOR, false, false, checkNonNegativeInteger, decodeSelectorField},
+		{MUST_BE_FRESH, false, false, checkFlag, decodeSelectorField},
+	}
+	metaInfoRules = []fieldRule{
+		{CONTENT_TYPE, false, false, checkNonNegativeInteger, decodeMetaField},
+		{FRESHNESS_PERIOD, false, false, checkNonNegativeInteger, decodeMetaField},
+		{FINAL_BLOCK_ID, false, false, validateFinalBlockId, decodeMetaField},
+	}
+	signatureInfoRules = []fieldRule{
+		{SIGNATURE_TYPE, true, false, checkNonNegativeInteger, decodeSignatureInfoField},
+		{KEY_LOCATOR, false, false, validateKeyLocator, decodeSignatureInfoField},
+		{VALIDITY_PERIOD, false, false, nil, nil},
+	}
+)
+
+//the format of the interest t: v0.2 when it holds Selectors, v0.3 otherwise.
+//an interest holding only fields both formats have (Name, Nonce, InterestLifetime) is read
+//as v0.3, like the current forwarders do, so it can not be satisfied by a longer name.
+//a framing error stops the walk, the decoding reports it
+func interestFormat(t Tlv) packets.InterestFormat {
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			break
+		}
+		if f.T == SELECTORS {
+			return packets.InterestFormatV02
+		}
+		i += ts + ls + int(f.L)
+	}
+	return packets.InterestFormatV03
+}
+
+//an interest holding Selectors can not hold the fields only v0.3 has (CanBePrefix, MustBeFresh,
+//ForwardingHint, HopLimit, ApplicationParameters), whether they are critical or not: a
+//v0.2 decoder would drop the non-critical ones. the error is an ErrInterestFormat with the offset
+//of the v0.3 field in the value of t. a framing error stops the walk, the decoding reports it
+func checkInterestFormat(t Tlv) error {
+	if interestFormat(t) != packets.InterestFormatV02 {
+		return nil
+	}
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return nil
+		}
+		if ruleIndex(interestV03Rules, f.T) >= 0 && ruleIndex(interestV02Rules, f.T) < 0 {
+			return &TlvError{Type: f.T, Offset: i, Err: ErrInterestFormat}
+		}
+		i += ts + ls + int(f.L)
+	}
+	return nil
+}
+
+//the rules of the interest t, according to its format
+func interestRulesOf(t Tlv) []fieldRule {
+	if interestFormat(t) == packets.InterestFormatV02 {
+		return interestV02Rules
+	}
+	return interestV03Rules
+}
+
+//index of the rule of the type t, -1 if t has none
+func ruleIndex(rules []fieldRule, t uint64) int {
+	for i := range rules {
+		if rules[i].typ == t {
+			return i
+		}
+	}
+	return -1
+}
+
+//follows the fields of a tlv one by one to check their order, repetition and presence.
+//in strict mode a field can not come before the field matched by the one preceding it,
+//with anyOrder the fields are recognized wherever they are. in both modes a field that is
+//not repeatable can only be there once
+type fieldTracker struct {
+	rules    []fieldRule
+	anyOrder bool
+	seen     uint64 //bit i is set once rules[i] matched, no table has 64 rules
+	last     int    //the rule matched by the previous field
+}
+
+func newFieldTracker(rules []fieldRule, anyOrder bool) fieldTracker {
+	return fieldTracker{rules: rules, anyOrder: anyOrder, last: -1}
+}
+
+//gives back the rule of the next field, of type t, -1 if t has no rule.
+//the error is ErrDuplicateField or ErrFieldOrder when the field can not come here
+func (k *fieldTracker) add(t uint64) (int, error) {
+	r := ruleIndex(k.rules, t)
+	if r < 0 {
+		return -1, nil
+	}
+	switch {
+	case k.seen&(1<<uint(r)) != 0 && !k.rules[r].repeatable:
+		return r, ErrDuplicateField
+	case !k.anyOrder && r < k.last:
+		return r, ErrFieldOrder
+	}
+	k.seen |= 1 << uint(r)
+	k.last = r
+	return r, nil
+}
+
+//the type of the first required field that was not seen
+func (k *fieldTracker) missing() (uint64, bool) {
+	for i := range k.rules {
+		if k.rules[i].required && k.seen&(1<<uint(i)) == 0 {
+			return k.rules[i].typ, true
+		}
+	}
+	return 0, false
+}
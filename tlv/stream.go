@@ -0,0 +1,163 @@
+package tlv
+
+import (
+	"errors"
+	"io"
+
+	"ndn-router/nfd/tlv/packets"
+)
+
+//MAX_NDN_PACKET_SIZE, the default limit for packets read from a stream
+const MaxPacketSize = 8800
+
+//Reader pulls whole outer tlvs (interest, data ...) off a stream (tcp, unix socket ...)
+//where packets can arrive split across reads or several in a single read
+type Reader struct {
+	r       io.Reader
+	buf     []byte
+	start   int   //first byte not handed out yet
+	end     int   //end of the bytes read from r
+	offset  int64 //position of buf[start] in the stream, used in errors
+	maxSize int
+	err     error //sticky error from r
+}
+
+//creates a reader that accepts packets up to MaxPacketSize
+func NewReader(r io.Reader) *Reader {
+	return NewReaderSize(r, MaxPacketSize)
+}
+
+//creates a reader that accepts packets up to maxSize bytes (type and length included),
+//like DecodeOptions a maxSize <= 0 means MaxPacketSize
+func NewReaderSize(r io.Reader, maxSize int) *Reader {
+	if maxSize <= 0 {
+		maxSize = MaxPacketSize
+	}
+	return &Reader{
+		r:       r,
+		maxSize: maxSize,
+	}
+}
+
+//returns the wire encoding of the next outer tlv.
+//the slice points into the reader's buffer and is only valid until the next call,
+//copy it if it has to be kept.
+//at the end of the stream io.EOF is returned, or io.ErrUnexpectedEOF if the stream
+//stops in the middle of a packet
+func (r *Reader) ReadWire() ([]byte, error) {
+	for {
+		pending := r.buf[r.start:r.end]
+		t, l, ts, ls, err := readHeader(pending, false)
+		if err == nil {
+			if ts+ls > r.maxSize || l > uint64(r.maxSize-ts-ls) {
+				return nil, &TlvError{Type: t, Offset: int(r.offset), Err: ErrPacketTooLarge}
+			}
+			size := ts + ls + int(l)
+			if len(pending) >= size {
+				r.start += size
+				r.offset += int64(size)
+				return pending[:size], nil
+			}
+		} else if !errors.Is(err, ErrTruncated) {
+			//the stream is out of sync, there is no way to find the next packet
+			return nil, errorAt(err, int(r.offset))
+		}
+		//the packet is not complete yet
+		if err := r.fill(); err != nil {
+			if err == io.EOF && len(pending) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+}
+
+//reads the next packet and decodes it, a packet that cannot be decoded gives the error of Decode.
+//the packet gets its own copy of the bytes so it can outlive the reader's buffer
+func (r *Reader) ReadPacket() (packets.NdnPacket, error) {
+	wire, err := r.ReadWire()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeWithOptions(append([]byte(nil), wire...), DecodeOptions{MaxPacketSize: r.maxSize})
+}
+
+//reads more bytes from the underlying reader, moving the pending bytes to the
+//front of the buffer first
+func (r *Reader) fill() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.buf == nil {
+		//the buffer always holds a whole header (type and length take at most 9 bytes each),
+		//so a packet bigger than maxSize is reported as ErrPacketTooLarge
+		size := r.maxSize
+		if size < 18 {
+			size = 18
+		}
+		r.buf = make([]byte, size)
+	}
+	if r.start > 0 {
+		r.end = copy(r.buf, r.buf[r.start:r.end])
+		r.start = 0
+	}
+	//a reader may return 0 bytes without an error, try a few times before giving up
+	for i := 0; i < 100; i++ {
+		n, err := r.r.Read(r.buf[r.end:])
+		r.end += n
+		if err != nil {
+			r.err = err
+		}
+		if n > 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return io.ErrNoProgress
+}
+
+//Writer writes packets to a stream, each packet with a single call to Write
+//so that packets from several writers on the same connection do not get mixed
+type Writer struct {
+	w   io.Writer
+	buf []byte //reused from one packet to the next
+	//the options of the encoding, to write the interests in the format the other end understands
+	Options EncodeOptions
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+//encodes the packet and writes it to the stream
+func (w *Writer) WritePacket(packet packets.NdnPacket) error {
+	packet = w.Options.apply(packet)
+	size, err := EncodedLength(packet)
+	if err != nil {
+		return err
+	}
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	n, err := EncodeTo(packet, w.buf[:size])
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(w.buf[:n])
+	return err
+}
+
+//writes an already encoded packet, b must hold exactly one complete tlv
+func (w *Writer) WriteWire(b []byte) error {
+	t, err, ts, ls := TlvFromBytes(b)
+	if err != nil {
+		return err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return errors.New("Writer : --- trailing bytes after the packet ---")
+	}
+	_, err = w.w.Write(b)
+	return err
+}
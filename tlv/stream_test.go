@@ -0,0 +1,30 @@
+package tlv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestReaderSmallSizes(t *testing.T) {
+	packet := NewNestedElement(INTEREST, NewElement(NAME, nil)).Encode()
+	for _, size := range []int{-1, 0} {
+		r := NewReaderSize(bytes.NewReader(packet), size)
+		if wire, err := r.ReadWire(); err != nil || !bytes.Equal(wire, packet) {
+			t.Errorf("size %d: got % x, %v", size, wire, err)
+		}
+	}
+	//smaller than the header of the packet
+	for _, size := range []int{1, 3} {
+		r := NewReaderSize(bytes.NewReader(packet), size)
+		if _, err := r.ReadWire(); !errors.Is(err, ErrPacketTooLarge) {
+			t.Errorf("size %d: %v, want ErrPacketTooLarge", size, err)
+		}
+	}
+	//a header of 18 bytes with a reader of 10 bytes
+	long := []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 5, 0xff, 0, 0, 0, 0, 0, 0, 0, 1}
+	r := NewReaderSize(bytes.NewReader(long), 10)
+	if _, err := r.ReadWire(); !errors.Is(err, ErrPacketTooLarge) {
+		t.Errorf("long header: %v, want ErrPacketTooLarge", err)
+	}
+}
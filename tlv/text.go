@@ -0,0 +1,267 @@
+package tlv
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//the text notation writes a tlv as its type followed by its value:
+//
+//	INTEREST {
+//		NAME { NAME_COMPONENT "foo" }   // a nested tlv
+//		NONCE x"61626364"               // bytes in hex
+//		INTEREST_LIFETIME 4000          // a NonNegativeInteger
+//		MUST_BE_FRESH {}                // an empty value
+//	}
+//
+//a type is a name known by the registry (the constants of constants.go, see RegisterType) or a number (0x80, 128), a value is a
+//list of tlvs in braces, a quoted string (with the escapes of Go), x"..." holding hex digits
+//(spaces are allowed between them) or an unsigned integer, written as a NonNegativeInteger
+//with the shortest encoding. comments run from // to the end of the line.
+//the lengths are computed from the values
+
+//types whose value is printed as a string when it is text, along with the KindString ones
+var textTypes = map[uint64]bool{
+	NAME_COMPONENT: true,
+	CONTENT:        true,
+}
+
+//ParseText turns tlvs written in the text notation into their wire encoding,
+//several tlvs are encoded one after the other
+func ParseText(s string) ([]byte, error) {
+	p := &textParser{s: s}
+	elements, err := p.elements(false)
+	if err != nil {
+		return nil, err
+	}
+	result := []byte{}
+	for _, e := range elements {
+		result = append(result, e.Encode()...)
+	}
+	return result, nil
+}
+
+//FormatText writes the tlvs of b in the text notation, one field per line, with the
+//names of the registry.
+//the tlvs are parsed like ParseElements does, so ParseText gives b back as long as it
+//uses the shortest VAR-NUMBERs and the values of unknown types are not mistaken for tlvs
+func FormatText(b []byte) (string, error) {
+	elements, err := ParseElements(b)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, e := range elements {
+		formatElement(&sb, e, 0)
+	}
+	return sb.String(), nil
+}
+
+func formatElement(sb *strings.Builder, e *Element, indent int) {
+	sb.WriteString(strings.Repeat("\t", indent))
+	sb.WriteString(TypeName(e.Type))
+	switch {
+	case e.IsNested() && len(e.Children) == 0:
+		sb.WriteString(" {}\n")
+	case e.IsNested():
+		sb.WriteString(" {\n")
+		for _, c := range e.Children {
+			formatElement(sb, c, indent+1)
+		}
+		sb.WriteString(strings.Repeat("\t", indent) + "}\n")
+	default:
+		sb.WriteString(" " + formatValue(e.Type, e.Value) + "\n")
+	}
+}
+
+func formatValue(t uint64, v []byte) string {
+	if isKind(t, KindNonNegativeInteger) {
+		x, err := DecodeNonNegativeIntegerValue(Tlv{T: t, L: uint64(len(v)), V: v})
+		//a longer encoding than needed is kept as it is
+		if err == nil && len(v) == nonNegativeIntegerSize(x) {
+			return strconv.FormatUint(x, 10)
+		}
+	}
+	if (textTypes[t] || isKind(t, KindString)) && isPrintable(v) {
+		return strconv.Quote(string(v))
+	}
+	return "x\"" + hex.EncodeToString(v) + "\""
+}
+
+func isPrintable(v []byte) bool {
+	if !utf8.Valid(v) {
+		return false
+	}
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+type textParser struct {
+	s   string
+	pos int
+}
+
+//reads the tlvs up to the end of the text, or up to the closing brace if nested is set
+func (p *textParser) elements(nested bool) ([]*Element, error) {
+	result := []*Element{}
+	for {
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			if nested {
+				return nil, p.errorf("missing }")
+			}
+			return result, nil
+		}
+		if p.s[p.pos] == '}' {
+			if !nested {
+				return nil, p.errorf("unexpected }")
+			}
+			p.pos++
+			return result, nil
+		}
+		e, err := p.element()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+}
+
+func (p *textParser) element() (*Element, error) {
+	start := p.pos
+	word := p.word()
+	if word == "" {
+		return nil, p.errorf("expected a type")
+	}
+	info, ok := LookupTypeName(word)
+	t := info.Type
+	if !ok {
+		var err error
+		t, err = strconv.ParseUint(word, 0, 64)
+		if err != nil || t == 0 || t > 0xFFFFFFFF {
+			p.pos = start
+			return nil, p.errorf("bad type %q", word)
+		}
+	}
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return nil, p.errorf("missing value")
+	}
+	switch c := p.s[p.pos]; {
+	case c == '{':
+		p.pos++
+		children, err := p.elements(true)
+		if err != nil {
+			return nil, err
+		}
+		return NewNestedElement(t, children...), nil
+	case c == '"':
+		v, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return NewElement(t, []byte(v)), nil
+	case c == 'x' && strings.HasPrefix(p.s[p.pos:], "x\""):
+		p.pos++
+		v, err := p.hexString()
+		if err != nil {
+			return nil, err
+		}
+		return NewElement(t, v), nil
+	default:
+		start := p.pos
+		word := p.word()
+		x, err := strconv.ParseUint(word, 0, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("bad value %q", word)
+		}
+		return NewElement(t, EncodeNonNegativeInteger(x)), nil
+	}
+}
+
+//reads a type name or a number
+func (p *textParser) word() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+//reads a string in Go syntax
+func (p *textParser) quoted() (string, error) {
+	start := p.pos
+	for i := p.pos + 1; i < len(p.s) && p.s[i] != '\n'; i++ {
+		switch p.s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(p.s[start : i+1])
+			if err != nil {
+				return "", p.errorf("bad string %s", p.s[start:i+1])
+			}
+			p.pos = i + 1
+			return v, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+//reads the hex digits between quotes, ignoring spaces
+func (p *textParser) hexString() ([]byte, error) {
+	end := strings.IndexByte(p.s[p.pos+1:], '"')
+	if end < 0 {
+		return nil, p.errorf("unterminated hex string")
+	}
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, p.s[p.pos+1:p.pos+1+end])
+	v, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, p.errorf("bad hex string")
+	}
+	p.pos += end + 2
+	return v, nil
+}
+
+//skips spaces and comments
+func (p *textParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch {
+		case strings.HasPrefix(p.s[p.pos:], "//"):
+			end := strings.IndexByte(p.s[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.s)
+				return
+			}
+			p.pos += end + 1
+		case strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0:
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+//the error with the line and column of the current position
+func (p *textParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.s[:p.pos], "\n") + 1
+	col := p.pos - strings.LastIndexByte(p.s[:p.pos], '\n')
+	return fmt.Errorf("ParseText : --- line %d col %d: %s ---", line, col, fmt.Sprintf(format, args...))
+}
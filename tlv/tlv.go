@@ -1,7 +1,6 @@
 package tlv
 
 import (
-	"encoding/binary"
 	"io"
 )
 
@@ -13,27 +12,76 @@ type Tlv struct {
 
 //tlv reader, reads a slice of bytes and gives back a tlv
 //mostly used for the outer-most tlv (interest, data, nack)
+//a malformed tlv gives back an empty Tlv and a *TlvError. the size of the tlv is not limited,
+//the packet decoders check it against DecodeOptions.MaxPacketSize
 func TlvFromBytes(packet []byte) (result Tlv, err error, ts int, ls int) {
+	return tlvFromBytes(packet, noSizeLimit, false)
+}
+
+//a size no tlv can go over, the length of a slice being an int
+const noSizeLimit = int(^uint(0) >> 1)
+
+//same as TlvFromBytes with maxSize as the limit for the whole tlv, canonical rejecting the
+//types and lengths that do not use the shortest VAR-NUMBER (see readHeader)
+func tlvFromBytes(packet []byte, maxSize int, canonical bool) (result Tlv, err error, ts int, ls int) {
 	var t, l uint64
+	t, l, ts, ls, err = readHeader(packet, canonical)
+	if err != nil {
+		return Tlv{}, err, 0, 0
+	}
+	//compare as uint64 so a huge length cannot wrap around when converted to int
+	if ts+ls > maxSize || l > uint64(maxSize-ts-ls) {
+		return Tlv{}, &TlvError{Type: t, Offset: 0, Err: ErrPacketTooLarge}, 0, 0
+	}
+	if l > uint64(len(packet)-ts-ls) {
+		return Tlv{}, &TlvError{Type: t, Offset: 0, Err: ErrLengthOverflow}, 0, 0
+	}
+	result = Tlv{t, l, packet[ts+ls : ts+ls+int(l)]}
+	return
+}
+
+//reads the type and length of the tlv at the start of packet, without looking at the value
+//ts and ls are the number of bytes used by the type and the length.
+//non-minimal VAR-NUMBERs are accepted, unless canonical is set (ErrBadVarNumber)
+func readHeader(packet []byte, canonical bool) (t, l uint64, ts, ls int, err error) {
 	//get type
-	t, ts = varDecoding(packet)
+	t, ts, err = DecodeVarNumber(packet, canonical)
+	if err != nil {
+		return 0, 0, 0, 0, &TlvError{Offset: 0, Err: err}
+	}
+	if t == 0 || t > 0xFFFFFFFF {
+		//type 0 is reserved and types are limited to 32 bits
+		return 0, 0, 0, 0, &TlvError{Type: t, Offset: 0, Err: ErrBadVarNumber}
+	}
 	//get length
-	l, ls = varDecoding(packet[ts:])
-	result = Tlv{t, l, packet[ts+ls : ts+ls+int(l)]}
-	err = nil //need to handle byte reading errors
+	l, ls, err = DecodeVarNumber(packet[ts:], canonical)
+	if err != nil {
+		return 0, 0, 0, 0, &TlvError{Type: t, Offset: 0, Err: err}
+	}
 	return
 }
 
 //tlv parser reads a stream of bytes and gives back a slice of tlvs (name, nonce, lifetime ...)
-//input is usually the value of the outer most tlv
+//input is usually the value of the outer most tlv, its size is not limited
 func ParseTlvsFromBytes(packet []byte) (result []Tlv, err error) {
+	result, err = parseTlvs(nil, packet, noSizeLimit, false)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//same as ParseTlvsFromBytes but appends to dst, so the decoders can reuse pooled slices.
+//the tlvs read before an error are kept in the result, maxSize and canonical apply to each tlv
+func parseTlvs(dst []Tlv, packet []byte, maxSize int, canonical bool) (result []Tlv, err error) {
+	result = dst
 	tmp := Tlv{}
 	var ts, ls int
 	for i := 0; i < len(packet); i += ts + ls + int(tmp.L) {
 		//get the current tlv
-		tmp, err, ts, ls = TlvFromBytes(packet[i:])
+		tmp, err, ts, ls = tlvFromBytes(packet[i:], maxSize, canonical)
 		if err != nil {
-			return
+			return result, errorAt(err, i)
 		}
 		result = append(result, tmp) // add the tlv to results
 	}
@@ -45,9 +93,14 @@ func ParseTlvsFromBytes(packet []byte) (result []Tlv, err error) {
 // type and length may need to be encoded on multiple bytes
 
 func TlvToBytes(t Tlv, byteStream io.Writer) error {
-	varEncoding(t.T, byteStream)
-	varEncoding(t.L, byteStream)
-	return binary.Write(byteStream, binary.BigEndian, t.V)
+	var header [18]byte //type and length take at most 9 bytes each
+	n := varEncoding(t.T, header[:])
+	n += varEncoding(t.L, header[n:])
+	if _, err := byteStream.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := byteStream.Write(t.V)
+	return err
 }
 
 func TlvsToBytes(t []Tlv, byteStream io.Writer) error {
@@ -64,12 +117,12 @@ func TlvsToBytes(t []Tlv, byteStream io.Writer) error {
 func countTlv(packet []byte) (count int) {
 	var i int // initilized to the zero value
 	for len(packet[i:]) != 0 {
-		//get type
-		_, j := varDecoding(packet[i:])
-		i += j
-		//get length
-		l, j := varDecoding(packet[i:])
-		i += j + int(l) // move the index to the next tlv
+		//stop counting at the first malformed tlv
+		t, err, ts, ls := TlvFromBytes(packet[i:])
+		if err != nil {
+			return
+		}
+		i += ts + ls + int(t.L) // move the index to the next tlv
 		count++
 	}
 	return
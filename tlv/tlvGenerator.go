@@ -1,9 +1,679 @@
-package tlv
+//go:build ignore
+// +build ignore
 
-// import "name"
+//tlvGenerator reads a description of tlv structures and writes the Go types and
+//the codec for them, in the style of the hand-written Interest and Data codec.
+//it is run by go generate from the tlv directory:
+//
+//	go run tlvGenerator.go -in lp.tlvspec -out lp_gen.go -unknown ndnlp
+//
+//the description is made of rules, one per line (indented lines continue the previous one),
+//';' starts a comment:
+//
+//	LpPacket   = LP_PACKET(0x64) Sequence? FragIndex? Nack? Ack* Fragment?
+//	Nack       = NACK(0x0320) NackReason?
+//	Sequence   = SEQUENCE(0x51) uint64
+//	NackReason = NACK_REASON(0x0321) nonneg
+//
+//a rule gives the name of the Go type or field, the name and number of the tlv type
+//and either a value kind or the list of the fields it holds, in wire order.
+//a field is required, optional (?), repeated (*) or repeated at least once (+).
+//value kinds are:
+//  - nonneg                 : NonNegativeInteger, uint64
+//  - uint8 ... uint64       : big endian integer of exactly that size
+//  - bytes                  : []byte, decoded values point into the input
+//  - flag                   : bool, an empty tlv that is present when true
+//  - name                   : name.Name, a list of name components
+//
+//for every rule holding fields a struct is generated along with its ...Length, write...
+//and decode...Into functions. the rules used by no other rule are the packets, they get
+//EncodedLength and EncodeTo methods and a Decode... function. the types are added to the
+//registry under the name of their constant.
+//
+//the decoders skip the unknown fields that are not critical (IsCritical). with -unknown ndnlp
+//the packets follow the NDNLPv2 rule for their header fields instead: an unknown field can only
+//be ignored if its type is in [800, 959] and its two least significant bits are 0
+package main
 
-// prefix := name.New("nist","gov")
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
 
-// func GenerateTlv(type int64) (NdnPacket){
+//the kinds of values a leaf rule can hold and the Go type used for them
+var kinds = map[string]string{
+	"nonneg": "uint64",
+	"uint8":  "uint8",
+	"uint16": "uint16",
+	"uint32": "uint32",
+	"uint64": "uint64",
+	"bytes":  "[]byte",
+	"flag":   "bool",
+	"name":   "name.Name",
+}
 
-// }
+type rule struct {
+	name   string
+	cons   string //name of the type constant
+	typ    uint64
+	kind   string //value kind of a leaf, empty for a rule holding fields
+	fields []field
+	used   bool //the rule is a field of another rule
+	line   int
+}
+
+type field struct {
+	rule     *rule
+	optional bool
+	repeated bool
+	atLeast  bool //repeated at least once
+}
+
+func main() {
+	in := flag.String("in", "", "file describing the tlv structures")
+	out := flag.String("out", "", "generated Go file")
+	pkg := flag.String("package", "tlv", "package of the generated file")
+	unknown := flag.String("unknown", "critical", "rule for the unknown fields of the packets: critical or ndnlp")
+	flag.Parse()
+	if *in == "" || *out == "" || *unknown != "critical" && *unknown != "ndnlp" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rules, err := parseSpec(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s:%v", *in, err)
+	}
+	src, err := generate(rules, *pkg, *in, *unknown)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//reads the rules, checks that every field refers to a rule and that names and types are unique
+func parseSpec(r io.Reader) ([]*rule, error) {
+	type line struct {
+		text string
+		num  int
+	}
+	lines := []line{}
+	scanner := bufio.NewScanner(r)
+	for num := 1; scanner.Scan(); num++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, ';'); i >= 0 {
+			text = text[:i]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(lines) > 0 && (text[0] == ' ' || text[0] == '\t') {
+			lines[len(lines)-1].text += " " + text
+			continue
+		}
+		lines = append(lines, line{text, num})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	rules := []*rule{}
+	byName := map[string]*rule{}
+	consts := map[string]bool{}
+	types := map[uint64]string{}
+	for _, l := range lines {
+		tokens := strings.Fields(l.text)
+		if len(tokens) < 3 || tokens[1] != "=" {
+			return nil, fmt.Errorf("%d: expected \"Name = TYPE(number) ...\"", l.num)
+		}
+		r := &rule{name: tokens[0], line: l.num}
+		if !isIdentifier(r.name) || !unicode.IsUpper(rune(r.name[0])) {
+			return nil, fmt.Errorf("%d: %q is not an exported Go name", l.num, r.name)
+		}
+		if byName[r.name] != nil {
+			return nil, fmt.Errorf("%d: %s is defined twice", l.num, r.name)
+		}
+		open := strings.IndexByte(tokens[2], '(')
+		if open <= 0 || !strings.HasSuffix(tokens[2], ")") {
+			return nil, fmt.Errorf("%d: expected TYPE(number), got %q", l.num, tokens[2])
+		}
+		r.cons = tokens[2][:open]
+		if !isIdentifier(r.cons) || consts[r.cons] {
+			return nil, fmt.Errorf("%d: bad or duplicate type name %q", l.num, r.cons)
+		}
+		typ, err := strconv.ParseUint(tokens[2][open+1:len(tokens[2])-1], 0, 64)
+		if err != nil || typ == 0 || typ > 0xFFFFFFFF {
+			return nil, fmt.Errorf("%d: bad type number in %q", l.num, tokens[2])
+		}
+		if other, dup := types[typ]; dup {
+			return nil, fmt.Errorf("%d: type 0x%x already used by %s", l.num, typ, other)
+		}
+		r.typ = typ
+		consts[r.cons] = true
+		types[typ] = r.name
+		if len(tokens) == 4 {
+			if _, ok := kinds[tokens[3]]; ok {
+				r.kind = tokens[3]
+			}
+		}
+		rules = append(rules, r)
+		byName[r.name] = r
+	}
+
+	//the fields are resolved once all the rules are known, so a rule can be used before it is defined
+	for i, l := range lines {
+		r := rules[i]
+		if r.kind != "" {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, tok := range strings.Fields(l.text)[3:] {
+			f := field{}
+			switch tok[len(tok)-1] {
+			case '?':
+				f.optional = true
+			case '*':
+				f.repeated = true
+			case '+':
+				f.repeated, f.atLeast = true, true
+			}
+			ref := strings.TrimRight(tok, "?*+")
+			f.rule = byName[ref]
+			if f.rule == nil {
+				if _, isKind := kinds[ref]; isKind {
+					return nil, fmt.Errorf("%d: a value kind must be the only element of a rule", l.num)
+				}
+				return nil, fmt.Errorf("%d: unknown rule %q", l.num, ref)
+			}
+			if f.rule == r {
+				return nil, fmt.Errorf("%d: %s holds itself", l.num, r.name)
+			}
+			if seen[ref] {
+				return nil, fmt.Errorf("%d: %s is used twice in %s", l.num, ref, r.name)
+			}
+			if f.rule.kind == "flag" && f.repeated {
+				return nil, fmt.Errorf("%d: flag %s cannot be repeated", l.num, ref)
+			}
+			seen[ref] = true
+			f.rule.used = true
+			r.fields = append(r.fields, f)
+		}
+	}
+	return rules, checkCycles(rules)
+}
+
+//a rule cannot hold itself, even through other rules, its struct would be infinite
+func checkCycles(rules []*rule) error {
+	state := map[*rule]int{} //1 being visited, 2 done
+	var visit func(r *rule) error
+	visit = func(r *rule) error {
+		switch state[r] {
+		case 1:
+			return fmt.Errorf("%d: %s holds itself", r.line, r.name)
+		case 2:
+			return nil
+		}
+		state[r] = 1
+		for _, f := range r.fields {
+			if err := visit(f.rule); err != nil {
+				return err
+			}
+		}
+		state[r] = 2
+		return nil
+	}
+	for _, r := range rules {
+		if err := visit(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || unicode.IsLetter(c) || i > 0 && unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+//code generation
+//
+//the generated functions take the struct by pointer so nothing is copied while encoding,
+//and decode into a struct given by the caller, reusing its slices
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	unknown string //rule for the unknown fields of the packets
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func generate(rules []*rule, pkg, specFile, unknown string) ([]byte, error) {
+	g := &generator{imports: map[string]bool{}, unknown: unknown}
+	g.printf("const (\n")
+	for _, r := range rules {
+		g.printf("%s = 0x%x\n", r.cons, r.typ)
+	}
+	g.printf(")\n\n")
+	g.printf("func init() {\n")
+	for _, r := range rules {
+		g.printf("mustRegisterType(TypeInfo{Type: %s, Name: %q, %s})\n", r.cons, r.cons, typeInfo(r))
+	}
+	g.printf("}\n\n")
+	hasStruct := false
+	for _, r := range rules {
+		if r.kind != "" {
+			continue
+		}
+		hasStruct = true
+		g.genStruct(r)
+		g.genLength(r)
+		g.genWrite(r)
+		g.genDecode(r)
+		if !r.used {
+			g.genPacket(r)
+		}
+	}
+	if !hasStruct {
+		return nil, errors.New("no rule holds fields, there is nothing to generate")
+	}
+
+	head := &bytes.Buffer{}
+	fmt.Fprintf(head, "// Code generated by tlvGenerator.go from %s; DO NOT EDIT.\n\n", specFile)
+	fmt.Fprintf(head, "package %s\n\nimport (\n", pkg)
+	for _, imp := range []string{"encoding/binary", "io", "ndn-router/nfd/tlv/name"} {
+		if g.imports[imp] {
+			fmt.Fprintf(head, "%q\n", imp)
+		}
+	}
+	fmt.Fprintf(head, ")\n\n")
+	src, err := format.Source(append(head.Bytes(), g.buf.Bytes()...))
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %v", err)
+	}
+	return src, nil
+}
+
+//the kind of the type in the registry, and its decoder for the fixed size integers
+func typeInfo(r *rule) string {
+	switch r.kind {
+	case "", "name":
+		return "Kind: KindNested"
+	case "nonneg":
+		return "Kind: KindNonNegativeInteger"
+	case "flag":
+		return "Kind: KindFlag"
+	case "bytes":
+		return "Kind: KindBytes"
+	}
+	size := map[string]int{"uint8": 1, "uint16": 2, "uint32": 4, "uint64": 8}[r.kind]
+	return fmt.Sprintf("Kind: KindBytes, Decode: fixedIntegerDecoder(%d)", size)
+}
+
+//the abnf of the rule, used as the comment of the generated type
+func grammar(r *rule) string {
+	parts := []string{r.name, "::=", r.cons, "TLV-LENGTH"}
+	for _, f := range r.fields {
+		suffix := ""
+		switch {
+		case f.atLeast:
+			suffix = "+"
+		case f.repeated:
+			suffix = "*"
+		case f.optional:
+			suffix = "?"
+		}
+		parts = append(parts, f.rule.name+suffix)
+	}
+	return strings.Join(parts, " ")
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func goType(r *rule) string {
+	if r.kind == "" {
+		return r.name
+	}
+	return kinds[r.kind]
+}
+
+//optional fields other than flags come with a Has... boolean, like the selectors
+func hasFlag(f field) bool {
+	return f.optional && f.rule.kind != "flag"
+}
+
+func (g *generator) genStruct(r *rule) {
+	g.printf("//%s\n", grammar(r))
+	g.printf("type %s struct {\n", r.name)
+	for _, f := range r.fields {
+		t := goType(f.rule)
+		if f.rule.kind == "name" {
+			g.imports["ndn-router/nfd/tlv/name"] = true
+		}
+		if f.repeated {
+			t = "[]" + t
+		}
+		if hasFlag(f) {
+			g.printf("Has%s bool\n", f.rule.name)
+		}
+		g.printf("%s %s\n", f.rule.name, t)
+	}
+	g.printf("}\n\n")
+}
+
+//the condition under which a field that is not repeated is encoded, empty if it always is
+func condition(f field, v string) string {
+	switch {
+	case f.rule.kind == "flag":
+		return v
+	case hasFlag(f):
+		return "p.Has" + f.rule.name
+	}
+	return ""
+}
+
+//size of the whole tlv of a field, v being the expression giving its value
+func sizeExpr(r *rule, v string) string {
+	switch r.kind {
+	case "nonneg":
+		return fmt.Sprintf("nonNegativeIntegerTlvSize(%s, %s)", r.cons, v)
+	case "uint8":
+		return fmt.Sprintf("tlvSize(%s, 1)", r.cons)
+	case "uint16":
+		return fmt.Sprintf("tlvSize(%s, 2)", r.cons)
+	case "uint32":
+		return fmt.Sprintf("tlvSize(%s, 4)", r.cons)
+	case "uint64":
+		return fmt.Sprintf("tlvSize(%s, 8)", r.cons)
+	case "bytes":
+		return fmt.Sprintf("tlvSize(%s, len(%s))", r.cons, v)
+	case "flag":
+		return fmt.Sprintf("tlvSize(%s, 0)", r.cons)
+	case "name":
+		return fmt.Sprintf("tlvSize(%s, nameLength(%s))", r.cons, v)
+	default:
+		return fmt.Sprintf("tlvSize(%s, %sLength(&%s))", r.cons, lowerFirst(r.name), v)
+	}
+}
+
+//statements writing the whole tlv of a field at b[n:]
+func (g *generator) writeStmt(r *rule, v string) {
+	switch r.kind {
+	case "nonneg":
+		g.printf("n += writeNonNegativeIntegerTlv(b[n:], %s, %s)\n", r.cons, v)
+	case "uint8":
+		g.printf("n += writeHeader(b[n:], %s, 1)\n", r.cons)
+		g.printf("b[n] = %s\n", v)
+		g.printf("n++\n")
+	case "uint16", "uint32", "uint64":
+		g.imports["encoding/binary"] = true
+		size := map[string]int{"uint16": 2, "uint32": 4, "uint64": 8}[r.kind]
+		g.printf("n += writeHeader(b[n:], %s, %d)\n", r.cons, size)
+		g.printf("binary.BigEndian.Put%s(b[n:], %s)\n", "Uint"+r.kind[4:], v)
+		g.printf("n += %d\n", size)
+	case "bytes":
+		g.printf("n += writeTlv(b[n:], %s, %s)\n", r.cons, v)
+	case "flag":
+		g.printf("n += writeHeader(b[n:], %s, 0)\n", r.cons)
+	case "name":
+		g.printf("n += writeHeader(b[n:], %s, nameLength(%s))\n", r.cons, v)
+		g.printf("for _, comp := range %s {\n", v)
+		g.printf("n += writeNameComponent(b[n:], comp)\n")
+		g.printf("}\n")
+	default:
+		g.printf("n += write%s(b[n:], &%s)\n", r.name, v)
+	}
+}
+
+func (g *generator) genLength(r *rule) {
+	g.printf("func %sLength(p *%s) int {\n", lowerFirst(r.name), r.name)
+	g.printf("l := 0\n")
+	for _, f := range r.fields {
+		v := "p." + f.rule.name
+		if f.repeated {
+			size := sizeExpr(f.rule, v+"[i]")
+			if !strings.Contains(size, "[i]") {
+				//fixed size elements
+				g.printf("l += len(%s) * %s\n", v, size)
+				continue
+			}
+			g.printf("for i := range %s {\n", v)
+			g.printf("l += %s\n", size)
+			g.printf("}\n")
+			continue
+		}
+		if c := condition(f, v); c != "" {
+			g.printf("if %s {\n", c)
+			g.printf("l += %s\n", sizeExpr(f.rule, v))
+			g.printf("}\n")
+			continue
+		}
+		g.printf("l += %s\n", sizeExpr(f.rule, v))
+	}
+	g.printf("return l\n")
+	g.printf("}\n\n")
+}
+
+func (g *generator) genWrite(r *rule) {
+	g.printf("func write%s(b []byte, p *%s) int {\n", r.name, r.name)
+	g.printf("n := writeHeader(b, %s, %sLength(p))\n", r.cons, lowerFirst(r.name))
+	for _, f := range r.fields {
+		v := "p." + f.rule.name
+		if f.repeated {
+			g.printf("for i := range %s {\n", v)
+			g.writeStmt(f.rule, v+"[i]")
+			g.printf("}\n")
+			continue
+		}
+		if c := condition(f, v); c != "" {
+			g.printf("if %s {\n", c)
+			g.writeStmt(f.rule, v)
+			g.printf("}\n")
+			continue
+		}
+		g.writeStmt(f.rule, v)
+	}
+	g.printf("return n\n")
+	g.printf("}\n\n")
+}
+
+//statements reading the value of the tlv f into dst, the values are checked like Decode
+//does and a bad one is a *ValueError
+func (g *generator) decodeStmt(r *rule, dst string) {
+	switch r.kind {
+	case "nonneg":
+		g.printf("if %s, err = DecodeNonNegativeIntegerValue(f); err != nil {\n", dst)
+		g.printf("return err\n")
+		g.printf("}\n")
+	case "uint8", "uint16", "uint32", "uint64":
+		size := map[string]int{"uint8": 1, "uint16": 2, "uint32": 4, "uint64": 8}[r.kind]
+		g.printf("v, err := DecodeFixedValue(f, %d)\n", size)
+		g.printf("if err != nil {\n")
+		g.printf("return err\n")
+		g.printf("}\n")
+		if r.kind == "uint8" {
+			g.printf("%s = v[0]\n", dst)
+		} else {
+			g.imports["encoding/binary"] = true
+			g.printf("%s = binary.BigEndian.%s(v)\n", dst, "Uint"+r.kind[4:])
+		}
+	case "bytes":
+		g.printf("%s = f.V\n", dst)
+	case "flag":
+		g.printf("if %s, err = DecodeFlagValue(f); err != nil {\n", dst)
+		g.printf("return err\n")
+		g.printf("}\n")
+	case "name":
+		//the name is validated first so its errors are framing errors with an offset,
+		//decodeNameInto only reads names of type NAME, the value is what matters
+		g.printf("if err := validateName(f); err != nil {\n")
+		g.printf("return errorAt(err, i+ts+ls)\n")
+		g.printf("}\n")
+		g.printf("if %s, err = decodeNameInto(%s[:0], Tlv{NAME, f.L, f.V}, &opts); err != nil {\n", dst, dst)
+		g.printf("return err\n")
+		g.printf("}\n")
+	default:
+		g.printf("if err := decode%sInto(f, &%s); err != nil {\n", r.name, dst)
+		g.printf("return errorAt(err, i+ts+ls)\n")
+		g.printf("}\n")
+	}
+}
+
+//the fields are read in place, without building a []Tlv, they must come in the order
+//of the rule. unknown types that can be ignored are skipped. the errors are the ones of
+//Validate, with offsets relative to the value of t
+func (g *generator) genDecode(r *rule) {
+	g.printf("//decodes t into p, reusing the slices p already holds\n")
+	g.printf("func decode%sInto(t Tlv, p *%s) error {\n", r.name, r.name)
+
+	//the slices are kept so their memory is reused
+	kept := []field{}
+	for _, f := range r.fields {
+		if f.repeated || f.rule.kind == "name" {
+			kept = append(kept, f)
+		}
+	}
+	for _, f := range kept {
+		g.printf("%s := p.%s[:0]\n", lowerFirst(f.rule.name), f.rule.name)
+	}
+	g.printf("*p = %s{}\n", r.name)
+	for _, f := range kept {
+		g.printf("p.%s = %s\n", f.rule.name, lowerFirst(f.rule.name))
+	}
+
+	hasName, required := false, false
+	for _, f := range r.fields {
+		hasName = hasName || f.rule.kind == "name"
+		required = required || !f.optional && (!f.repeated || f.atLeast)
+	}
+	if hasName {
+		g.printf("opts := DecodeOptions{}\n")
+	}
+	if required {
+		g.printf("var seen [%d]bool\n", len(r.fields))
+	}
+	if len(r.fields) > 0 {
+		g.printf("last := -1 //index of the last field read, they must come in order\n")
+	}
+	g.printf("var f Tlv\n")
+	g.printf("var ts, ls int\n")
+	g.printf("for i := 0; i < len(t.V); i += ts + ls + int(f.L) {\n")
+	g.printf("var err error\n")
+	g.printf("f, err, ts, ls = TlvFromBytes(t.V[i:])\n")
+	g.printf("if err != nil {\n")
+	g.printf("return errorAt(err, i)\n")
+	g.printf("}\n")
+	g.printf("switch f.T {\n")
+	for idx, f := range r.fields {
+		g.printf("case %s:\n", f.rule.cons)
+		g.printf("if last > %d {\n", idx)
+		g.printf("return &TlvError{Type: f.T, Offset: i, Err: ErrFieldOrder}\n")
+		g.printf("}\n")
+		if !f.repeated {
+			g.printf("if last == %d {\n", idx)
+			g.printf("return &TlvError{Type: f.T, Offset: i, Err: ErrDuplicateField}\n")
+			g.printf("}\n")
+		}
+		g.printf("last = %d\n", idx)
+		if required {
+			g.printf("seen[%d] = true\n", idx)
+		}
+		v := "p." + f.rule.name
+		if f.repeated {
+			var zero string
+			switch f.rule.kind {
+			case "":
+				zero = f.rule.name + "{}"
+			case "bytes", "name":
+				zero = "nil"
+			default:
+				zero = "0"
+			}
+			g.printf("%s = append(%s, %s)\n", v, v, zero)
+			v += "[len(" + v + ")-1]"
+		} else if hasFlag(f) {
+			g.printf("p.Has%s = true\n", f.rule.name)
+		}
+		g.decodeStmt(f.rule, v)
+	}
+	g.printf("default:\n")
+	if g.unknown == "ndnlp" && !r.used {
+		g.printf("//NDNLPv2: an unknown header field can only be ignored if its type is in [800, 959]\n")
+		g.printf("//and its two least significant bits are 0\n")
+		g.printf("if f.T < 800 || f.T > 959 || f.T&0x03 != 0 {\n")
+	} else {
+		g.printf("if IsCritical(f.T) {\n")
+	}
+	g.printf("return &UnknownTypeError{Type: f.T, Parent: t.T}\n")
+	g.printf("}\n")
+	g.printf("}\n")
+	g.printf("}\n")
+	for idx, f := range r.fields {
+		if f.optional || f.repeated && !f.atLeast {
+			continue
+		}
+		//like Validate, a missing field is reported at the end of the tlv that should hold it
+		g.printf("if !seen[%d] {\n", idx)
+		g.printf("return &TlvError{Type: %s, Offset: len(t.V), Err: ErrMissingField}\n", f.rule.cons)
+		g.printf("}\n")
+	}
+	g.printf("return nil\n")
+	g.printf("}\n\n")
+}
+
+//entry points for the rules that are not part of other rules
+func (g *generator) genPacket(r *rule) {
+	g.imports["io"] = true
+	lower := lowerFirst(r.name)
+	g.printf("//number of bytes the %s takes on the wire\n", r.name)
+	g.printf("func (p *%s) EncodedLength() int {\n", r.name)
+	g.printf("return tlvSize(%s, %sLength(p))\n", r.cons, lower)
+	g.printf("}\n\n")
+	g.printf("//writes the %s at the start of b, which must hold at least EncodedLength() bytes,\n", r.name)
+	g.printf("//and gives back the number of bytes written\n")
+	g.printf("func (p *%s) EncodeTo(b []byte) (int, error) {\n", r.name)
+	g.printf("if len(b) < p.EncodedLength() {\n")
+	g.printf("return 0, io.ErrShortBuffer\n")
+	g.printf("}\n")
+	g.printf("return write%s(b, p), nil\n", r.name)
+	g.printf("}\n\n")
+	g.printf("//decodes the single %s held by b into p, reusing the slices p already holds.\n", r.name)
+	g.printf("//byte fields point into b\n")
+	g.printf("func Decode%s(b []byte, p *%s) error {\n", r.name, r.name)
+	g.printf("t, err, ts, ls := TlvFromBytes(b)\n")
+	g.printf("if err != nil {\n")
+	g.printf("return err\n")
+	g.printf("}\n")
+	g.printf("if ts+ls+int(t.L) != len(b) {\n")
+	g.printf("return &TlvError{Type: t.T, Offset: ts + ls + int(t.L), Err: ErrTrailingBytes}\n")
+	g.printf("}\n")
+	g.printf("if t.T != %s {\n", r.cons)
+	g.printf("return unknownPacketError(t.T)\n")
+	g.printf("}\n")
+	g.printf("return errorAt(decode%sInto(t, p), ts+ls)\n", r.name)
+	g.printf("}\n\n")
+}
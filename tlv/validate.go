@@ -0,0 +1,236 @@
+package tlv
+
+//Validate checks that b holds a single well formed interest or data, without decoding it:
+//the outer tlv and every nested tlv are walked in place, the lengths, the presence and
+//order of the fields and the values of the fixed size and integer fields are checked.
+//nothing is allocated for a valid packet, so it can be used to drop bad packets before
+//Decode does any work. the error is the one Decode would report for the same problem
+//(*TlvError, *UnknownTypeError or *ValueError), with the default limits of Decode: the packet
+//is limited to MaxPacketSize and its names and excludes to DefaultMaxComponents components.
+//the fields of the schema are never deeper than DefaultMaxDepth and the unknown ones are not
+//walked, so the depth limit can not be reached
+func Validate(b []byte) error {
+	t, err, ts, ls := tlvFromBytes(b, MaxPacketSize, false)
+	if err != nil {
+		return err
+	}
+	if ts+ls+int(t.L) != len(b) {
+		return &TlvError{Type: t.T, Offset: ts + ls + int(t.L), Err: ErrTrailingBytes}
+	}
+	switch t.T {
+	case INTEREST:
+		err = checkInterestFormat(t)
+		if err == nil {
+			err = validateFields(t, interestRulesOf(t))
+		}
+		if err == nil {
+			err = checkParametersDigest(t)
+		}
+	case DATA:
+		err = validateFields(t, dataRules)
+	default:
+		return unknownPacketError(t.T)
+	}
+	return errorAt(err, ts+ls)
+}
+
+//walks the fields of t with the checks of its rules, in strict order, unknown non-critical
+//types are skipped. offsets are relative to the value of t
+func validateFields(t Tlv, rules []fieldRule) error {
+	k := newFieldTracker(rules, false)
+	for i := 0; i < len(t.V); {
+		f, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		r, err := k.add(f.T)
+		switch {
+		case err != nil:
+			return &TlvError{Type: f.T, Offset: i, Err: err}
+		case r < 0:
+			if IsCritical(f.T) {
+				return &UnknownTypeError{Type: f.T, Parent: t.T}
+			}
+		case rules[r].check != nil:
+			if err := rules[r].check(f); err != nil {
+				return errorAt(err, i+ts+ls)
+			}
+		}
+		i += ts + ls + int(f.L)
+	}
+	if typ, ok := k.missing(); ok {
+		//a missing field is reported at the end of the tlv that should hold it
+		return &TlvError{Type: typ, Offset: len(t.V), Err: ErrMissingField}
+	}
+	return nil
+}
+
+func checkNonNegativeInteger(t Tlv) error {
+	_, err := DecodeNonNegativeIntegerValue(t)
+	return err
+}
+
+func checkNonce(t Tlv) error {
+	_, err := DecodeFixedValue(t, 4)
+	return err
+}
+
+func checkHopLimit(t Tlv) error {
+	_, err := DecodeFixedValue(t, 1)
+	return err
+}
+
+func checkFlag(t Tlv) error {
+	_, err := DecodeFlagValue(t)
+	return err
+}
+
+//a name is a list of name components
+func validateName(t Tlv) error {
+	if err := checkComponentCount(t); err != nil {
+		return err
+	}
+	for i := 0; i < len(t.V); {
+		c, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		if err := checkNameComponent(c, t.T); err != nil {
+			return errorAt(err, i)
+		}
+		i += ts + ls + int(c.L)
+	}
+	return nil
+}
+
+//a name or an exclude holds at most DefaultMaxComponents components, the limit of Decode.
+//like Decode, all the components are framed before they are counted
+func checkComponentCount(t Tlv) error {
+	n := 0
+	for i := 0; i < len(t.V); n++ {
+		c, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		i += ts + ls + int(c.L)
+	}
+	if n > DefaultMaxComponents {
+		return &TlvError{Type: t.T, Offset: 0, Err: ErrTooManyComponents}
+	}
+	return nil
+}
+
+//a component of a name or a final block id (held by parent) can be of any type from 1 to
+//65535 (format v0.3), the implicit and parameters digests being 32 bytes.
+//the Any of the excludes is not a name component
+func checkNameComponent(c Tlv, parent uint64) error {
+	switch {
+	case c.T == IMPLICIT_DIGEST || c.T == PARAMETERS_SHA256_DIGEST:
+		_, err := DecodeFixedValue(c, 32)
+		return err
+	case c.T == ANY || c.T > 0xFFFF:
+		return &UnknownTypeError{Type: c.T, Parent: parent}
+	}
+	return nil
+}
+
+//an exclude is a list of components and Any
+func validateExclude(t Tlv) error {
+	if err := checkComponentCount(t); err != nil {
+		return err
+	}
+	for i := 0; i < len(t.V); {
+		c, err, ts, ls := TlvFromBytes(t.V[i:])
+		if err != nil {
+			return errorAt(err, i)
+		}
+		switch c.T {
+		case NAME_COMPONENT:
+		case ANY:
+			if err := checkFlag(c); err != nil {
+				return errorAt(err, i)
+			}
+		default:
+			return &UnknownTypeError{Type: c.T, Parent: t.T}
+		}
+		i += ts + ls + int(c.L)
+	}
+	return nil
+}
+
+//the final block id holds a single name component
+func validateFinalBlockId(t Tlv) error {
+	c, err, ts, ls := TlvFromBytes(t.V)
+	if err != nil {
+		return err
+	}
+	if err := checkNameComponent(c, t.T); err != nil {
+		return err
+	}
+	if ts+ls+int(c.L) != len(t.V) {
+		return &TlvError{Type: t.T, Offset: ts + ls + int(c.L), Err: ErrTrailingBytes}
+	}
+	return nil
+}
+
+func validateForwardingHint(t Tlv) error {
+	return validateFields(t, forwardingHintRules)
+}
+
+func validateSelectors(t Tlv) error {
+	return validateFields(t, selectorRules)
+}
+
+func validateMetaInfo(t Tlv) error {
+	return validateFields(t, metaInfoRules)
+}
+
+func validateSignatureInfo(t Tlv) error {
+	return validateFields(t, signatureInfoRules)
+}
+
+//the single field held by t (a key locator ...) and the size of its header.
+//an empty t is missing a field of type typ (ErrMissingField) and a field after the
+//first one is an error too (ErrTrailingBytes), the decoders check it the same way
+func singleField(t Tlv, typ uint64) (Tlv, int, error) {
+	if len(t.V) == 0 {
+		return Tlv{}, 0, &TlvError{Type: typ, Offset: 0, Err: ErrMissingField}
+	}
+	f, err, ts, ls := TlvFromBytes(t.V)
+	if err != nil {
+		return Tlv{}, 0, err
+	}
+	if ts+ls+int(f.L) != len(t.V) {
+		return Tlv{}, 0, &TlvError{Type: t.T, Offset: ts + ls + int(f.L), Err: ErrTrailingBytes}
+	}
+	return f, ts + ls, nil
+}
+
+//the publisher public key locator holds a single key locator
+func validatePublisherPublicKeyLocator(t Tlv) error {
+	k, hs, err := singleField(t, KEY_LOCATOR)
+	if err != nil {
+		return err
+	}
+	if k.T != KEY_LOCATOR {
+		return &UnknownTypeError{Type: k.T, Parent: t.T}
+	}
+	return errorAt(validateKeyLocator(k), hs)
+}
+
+// KeyLocator ::= KEY-LOCATOR-TYPE TLV-LENGTH (Name | KeyDigest)
+//a key locator of a non-critical type that is not known is ignored, like Decode does
+func validateKeyLocator(t Tlv) error {
+	v, hs, err := singleField(t, NAME)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.T == NAME:
+		return errorAt(validateName(v), hs)
+	case v.T == KEY_DIGEST || !IsCritical(v.T):
+		return nil
+	default:
+		return &UnknownTypeError{Type: v.T, Parent: t.T}
+	}
+}
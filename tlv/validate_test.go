@@ -0,0 +1,100 @@
+package tlv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+//packets on which Validate and the decoders must agree, in the text notation
+var corpus = []struct {
+	name string
+	text string
+}{
+	{"interest", `INTEREST { NAME { NAME_COMPONENT "a" } NONCE x"01020304" INTEREST_LIFETIME 4000 }`},
+	{"selectors", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS { MAX_SUFFIX_COMPONENTS 1 MUST_BE_FRESH {} } NONCE x"01020304" }`},
+	{"no nonce", `INTEREST { NAME { NAME_COMPONENT "a" } }`},
+	{"bad lifetime", `INTEREST { NAME { NAME_COMPONENT "a" } NONCE x"01020304" INTEREST_LIFETIME x"000001" }`},
+	{"any in name", `INTEREST { NAME { ANY "a" NAME_COMPONENT "bc" } NONCE x"01020304" }`},
+	{"typed component", `INTEREST { NAME { NAME_COMPONENT "a" 0x32 x"05" } NONCE x"01020304" }`},
+	{"selectors and can be prefix", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS { MUST_BE_FRESH {} } CAN_BE_PREFIX {} NONCE x"01020304" }`},
+	{"selectors and hop limit", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS {} NONCE x"01020304" HOP_LIMIT x"05" }`},
+	{"selectors and parameters", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS {} NONCE x"01020304" APPLICATION_PARAMETERS "p" }`},
+	{"bad name and nonce", `INTEREST { NAME { ANY "a" } SELECTORS { 0x11 "x" } NONCE x"0102" }`},
+	{"bad selectors and nonce", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS { 0x11 "x" } NONCE x"0102" }`},
+	{"bad parameters digest", `INTEREST { NAME { NAME_COMPONENT "a" PARAMETERS_SHA256_DIGEST x"` +
+		strings.Repeat("00", 32) + `" } NONCE x"01020304" APPLICATION_PARAMETERS "p" }`},
+	{"data", `DATA { NAME { NAME_COMPONENT "a" } CONTENT "hi" SIGNATURE_INFO { SIGNATURE_TYPE 0 } SIGNATURE_VALUE x"00" }`},
+	{"key locator name", `DATA { NAME { NAME_COMPONENT "a" } SIGNATURE_INFO { SIGNATURE_TYPE 1
+		KEY_LOCATOR { NAME { NAME_COMPONENT "key" } } } SIGNATURE_VALUE x"00" }`},
+	{"key locator non-critical", `DATA { NAME { NAME_COMPONENT "a" } SIGNATURE_INFO { SIGNATURE_TYPE 1
+		KEY_LOCATOR { 0x80 "x" } } SIGNATURE_VALUE x"00" }`},
+	{"key locator critical", `DATA { NAME { NAME_COMPONENT "a" } SIGNATURE_INFO { SIGNATURE_TYPE 1
+		KEY_LOCATOR { 0x81 "x" } } SIGNATURE_VALUE x"00" }`},
+	{"key locator trailing", `DATA { NAME { NAME_COMPONENT "a" } SIGNATURE_INFO { SIGNATURE_TYPE 1
+		KEY_LOCATOR { KEY_DIGEST x"01" 0x80 "x" } } SIGNATURE_VALUE x"00" }`},
+	{"key locator empty", `DATA { NAME { NAME_COMPONENT "a" } SIGNATURE_INFO { SIGNATURE_TYPE 1
+		KEY_LOCATOR {} } SIGNATURE_VALUE x"00" }`},
+	{"too many components", `INTEREST { NAME { ` + strings.Repeat(`NAME_COMPONENT "a" `, DefaultMaxComponents+1) + `} NONCE x"01020304" }`},
+	{"most components", `INTEREST { NAME { ` + strings.Repeat(`NAME_COMPONENT "a" `, DefaultMaxComponents) + `} NONCE x"01020304" }`},
+	{"exclude too many components", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS { EXCLUDE { ` +
+		strings.Repeat(`ANY {} `, DefaultMaxComponents+1) + `} } NONCE x"01020304" }`},
+	{"publisher key locator trailing", `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS { PUBLISHER_PUB_KEY_LOCATOR {
+		KEY_LOCATOR { KEY_DIGEST x"01" } KEY_LOCATOR { KEY_DIGEST x"02" } } } NONCE x"01020304" }`},
+}
+
+//the sentinel error err wraps, nil for nil
+func sentinel(err error) error {
+	for _, s := range []error{ErrTruncated, ErrLengthOverflow, ErrBadVarNumber, ErrPacketTooLarge,
+		ErrCriticalType, ErrTooDeep, ErrTooManyComponents, ErrFieldTooLarge, ErrBadValue, ErrFieldOrder,
+		ErrDuplicateField, ErrMissingField, ErrTrailingBytes, ErrParametersDigest, ErrInterestFormat, ErrUnknownPacketType} {
+		if errors.Is(err, s) {
+			return s
+		}
+	}
+	return err
+}
+
+func parseCorpus(t *testing.T, text string) []byte {
+	b, err := ParseText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestMixedInterestFormat(t *testing.T) {
+	b := parseCorpus(t, `INTEREST { NAME { NAME_COMPONENT "a" } SELECTORS {} NONCE x"01020304" HOP_LIMIT x"05" }`)
+	if _, err := Decode(b); !errors.Is(err, ErrInterestFormat) {
+		t.Errorf("Decode: %v", err)
+	}
+	if err := Validate(b); !errors.Is(err, ErrInterestFormat) {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := DecodeWith(b, NopHandler{}); !errors.Is(err, ErrInterestFormat) {
+		t.Errorf("DecodeWith: %v", err)
+	}
+}
+
+func TestValidateAgreesWithDecode(t *testing.T) {
+	for _, c := range corpus {
+		b := parseCorpus(t, c.text)
+		_, decodeErr := Decode(b)
+		if got, want := sentinel(Validate(b)), sentinel(decodeErr); got != want {
+			t.Errorf("%s: Validate gives %v, Decode %v", c.name, got, want)
+		}
+	}
+}
+
+//nothing is allocated for a valid packet, the errors are values built for the caller
+func TestValidateAllocs(t *testing.T) {
+	for _, c := range corpus {
+		b := parseCorpus(t, c.text)
+		if Validate(b) != nil {
+			continue
+		}
+		if allocs := testing.AllocsPerRun(100, func() { Validate(b) }); allocs != 0 {
+			t.Errorf("%s: Validate: %v allocs, want 0", c.name, allocs)
+		}
+	}
+}